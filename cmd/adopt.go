@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Save changes made to the git config back into config.yaml",
+	Long: `Take the keys of the live global git config that differ from the active
+profile (see 'git-context status') and write them to config.yaml, so they are
+not lost on the next switch.
+
+Changes are stored in the active profile unless --into names another profile
+or "global". Each change is confirmed interactively unless --all is given.`,
+	Args: cobra.NoArgs,
+	RunE: runAdopt,
+}
+
+var (
+	adoptInto string
+	adoptAll  bool
+)
+
+// runAdopt handles the 'adopt' command.
+func runAdopt(cmd *cobra.Command, args []string) error {
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	if cfg.Current == "" {
+		ui.PrintError("No active profile set, switch to a profile first")
+
+		return errors.New("no active profile")
+	}
+
+	target := adoptInto
+	if target == "" {
+		target = cfg.Current
+	}
+
+	if target != config.GlobalLayer {
+		if _, err := cfg.GetProfile(target); err != nil {
+			ui.PrintError(fmt.Sprintf("Profile not found: %v", err))
+
+			return errors.Wrap(err, "profile not found")
+		}
+	}
+
+	changes, err := adoptableChanges(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintHeader(fmt.Sprintf("Adopting Changes into %s", target))
+
+	adopted := 0
+
+	for _, change := range changes {
+		description := describeChange(change)
+
+		if !adoptAll {
+			confirm, err := ui.PromptConfirm(fmt.Sprintf("Adopt %s?", description))
+			if err != nil {
+				ui.PrintWarning("Adopt canceled")
+
+				return errors.Wrap(err, "failed to confirm change")
+			}
+
+			if !confirm {
+				ui.PrintInfo("Skipped " + change.Key)
+
+				continue
+			}
+		}
+
+		if err := adoptChange(cfg, target, change); err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to adopt %s: %v", change.Key, err))
+
+			return errors.Wrapf(err, "failed to adopt %s", change.Key)
+		}
+
+		ui.PrintInfo("Adopted " + description)
+
+		adopted++
+	}
+
+	if adopted == 0 {
+		ui.PrintSuccess("Nothing to adopt")
+
+		return nil
+	}
+
+	if err := cfg.SaveConfig(paths.ConfigFile); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to save config: %v", err))
+
+		return errors.Wrap(err, "failed to save config")
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Adopted %d change(s) into %s", adopted, target))
+
+	// Changes adopted into global may still be overridden by the profile
+	if remaining, err := adoptableChanges(cfg, paths); err == nil && len(remaining) > 0 {
+		ui.PrintWarning(fmt.Sprintf(
+			"Profile '%s' still differs from the git config, see 'git-context status'", cfg.Current,
+		))
+	}
+
+	return nil
+}
+
+// adoptableChanges returns the drift of the active profile, without the
+// profile marker which is not part of config.yaml.
+func adoptableChanges(cfg *config.Config, paths *config.Paths) ([]git.Change, error) {
+	changes, err := configDrift(cfg, paths, cfg.Current)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(changes, func(c git.Change) bool {
+		return c.Key == git.ProfileKey
+	}), nil
+}
+
+// adoptChange applies a live config change to global or a profile.
+func adoptChange(cfg *config.Config, target string, change git.Change) error {
+	if change.Kind == git.ChangeRemoved {
+		return cfg.UnsetValue(target, change.Key)
+	}
+
+	// A single-valued key may repeat in the live config, only the last value counts
+	values := change.Live
+	if len(change.Expected) == 1 && len(values) > 1 {
+		values = values[len(values)-1:]
+	}
+
+	return cfg.SetValue(target, change.Key, values)
+}
+
+// describeChange formats a change for display.
+func describeChange(change git.Change) string {
+	expected := strings.Join(change.Expected, ", ")
+	live := strings.Join(change.Live, ", ")
+
+	switch change.Kind {
+	case git.ChangeAdded:
+		return fmt.Sprintf("%s = %s (added)", change.Key, live)
+	case git.ChangeRemoved:
+		return fmt.Sprintf("%s (removed, was %s)", change.Key, expected)
+	default:
+		return fmt.Sprintf("%s: %s → %s", change.Key, expected, live)
+	}
+}
+
+func init() {
+	adoptCmd.Flags().StringVar(&adoptInto, "into", "", `profile to store the changes in, or "global" (default: the active profile)`)
+	adoptCmd.Flags().BoolVar(&adoptAll, "all", false, "adopt every change without asking")
+	rootCmd.AddCommand(adoptCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List git config backups",
+	Long: `List the backups taken before git-context wrote a git config, newest first,
+with the profile that was active at the time. Restore one with
+'git-context restore [id]'.
+
+The number of backups kept is set by settings.backupRetention in config.yaml.`,
+	Args: cobra.NoArgs,
+	RunE: runBackups,
+}
+
+// runBackups handles the 'backups' command.
+func runBackups(cmd *cobra.Command, args []string) error {
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	backups, err := backupStore(cfg, paths).List()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to list backups: %v", err))
+
+		return errors.Wrap(err, "failed to list backups")
+	}
+
+	ui.PrintHeader("Backups")
+
+	if len(backups) == 0 {
+		ui.PrintInfo("No backups yet, one is taken before each switch")
+
+		return nil
+	}
+
+	rows := make([][]string, len(backups))
+	for i, backup := range backups {
+		profile := backup.Profile
+		if profile == "" {
+			profile = "-"
+		}
+
+		rows[i] = []string{backup.ID, backup.Created.Format("2006-01-02 15:04:05"), profile, backup.Source}
+	}
+
+	ui.PrintTable([]string{"ID", "Date", "Profile", "File"}, rows)
+
+	return nil
+}
+
+// backupStore returns the store of git config backups.
+func backupStore(cfg *config.Config, paths *config.Paths) *config.BackupStore {
+	return config.NewBackupStore(paths.BackupsDir, cfg.Settings.BackupRetention)
+}
+
+// backupGitConfig backs up a git config about to be written. A failed backup
+// is reported but does not stop the write.
+func backupGitConfig(cfg *config.Config, paths *config.Paths, source, profile string) {
+	backup, err := backupStore(cfg, paths).Save(source, profile)
+
+	switch {
+	case err != nil:
+		ui.PrintWarning(fmt.Sprintf("Failed to backup git config: %v", err))
+	case backup != nil:
+		ui.PrintInfo(fmt.Sprintf("Backed up %s as %s", source, backup.ID))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aanogueira/git-context/internal/config"
@@ -36,7 +38,7 @@ func TestRunInit(t *testing.T) {
 		}
 
 		// Load and verify
-		loadedCfg, err := config.LoadConfig(configFile)
+		loadedCfg, err := config.LoadConfig(configFile, "")
 		if err != nil {
 			t.Fatalf("Failed to load config: %v", err)
 		}
@@ -73,7 +75,7 @@ func TestRunInit(t *testing.T) {
 		}
 
 		// Load config (simulating init on existing config)
-		loadedCfg, err := config.LoadConfig(configFile)
+		loadedCfg, err := config.LoadConfig(configFile, "")
 		if err != nil {
 			t.Fatalf("Failed to load existing config: %v", err)
 		}
@@ -111,7 +113,7 @@ func TestAddProfile(t *testing.T) {
 	t.Run("AddNewProfile", func(t *testing.T) {
 		t.Parallel()
 
-		cfg, _ := config.LoadConfig(configFile)
+		cfg, _ := config.LoadConfig(configFile, "")
 
 		profile := &config.Profile{
 			User: config.UserConfig{
@@ -131,7 +133,7 @@ func TestAddProfile(t *testing.T) {
 		}
 
 		// Verify profile was added
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 
 		p, err := loadedCfg.GetProfile("test")
 		if err != nil {
@@ -146,7 +148,7 @@ func TestAddProfile(t *testing.T) {
 	t.Run("AddDuplicateProfile", func(t *testing.T) {
 		t.Parallel()
 
-		cfg, _ := config.LoadConfig(configFile)
+		cfg, _ := config.LoadConfig(configFile, "")
 
 		profile := &config.Profile{
 			User: config.UserConfig{
@@ -195,7 +197,7 @@ func TestRemoveProfile(t *testing.T) {
 	t.Run("RemoveExistingProfile", func(t *testing.T) {
 		t.Parallel()
 
-		cfg, _ := config.LoadConfig(configFile)
+		cfg, _ := config.LoadConfig(configFile, "")
 
 		err := cfg.RemoveProfile("remove-test")
 		if err != nil {
@@ -208,7 +210,7 @@ func TestRemoveProfile(t *testing.T) {
 		}
 
 		// Verify profile was removed
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 
 		_, err = loadedCfg.GetProfile("remove-test")
 		if err == nil {
@@ -219,7 +221,7 @@ func TestRemoveProfile(t *testing.T) {
 	t.Run("RemoveNonExistentProfile", func(t *testing.T) {
 		t.Parallel()
 
-		cfg, _ := config.LoadConfig(configFile)
+		cfg, _ := config.LoadConfig(configFile, "")
 
 		err := cfg.RemoveProfile("nonexistent")
 		if err == nil {
@@ -242,7 +244,7 @@ func TestListProfiles(t *testing.T) {
 			t.Fatalf("Failed to save config: %v", err)
 		}
 
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 		profiles := loadedCfg.ListProfiles()
 
 		if len(profiles) != 0 {
@@ -274,7 +276,7 @@ func TestListProfiles(t *testing.T) {
 			t.Fatalf("Failed to save config: %v", err)
 		}
 
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 		listedProfiles := loadedCfg.ListProfiles()
 
 		if len(listedProfiles) != 3 {
@@ -328,7 +330,7 @@ func TestShowProfile(t *testing.T) {
 	t.Run("ShowExistingProfile", func(t *testing.T) {
 		t.Parallel()
 
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 
 		p, err := loadedCfg.GetProfile("test")
 		if err != nil {
@@ -355,7 +357,7 @@ func TestShowProfile(t *testing.T) {
 	t.Run("ShowNonExistentProfile", func(t *testing.T) {
 		t.Parallel()
 
-		loadedCfg, _ := config.LoadConfig(configFile)
+		loadedCfg, _ := config.LoadConfig(configFile, "")
 
 		_, err := loadedCfg.GetProfile("nonexistent")
 		if err == nil {
@@ -364,99 +366,6 @@ func TestShowProfile(t *testing.T) {
 	})
 }
 
-func TestProfileToGitConfig(t *testing.T) {
-	t.Parallel()
-
-	profile := &config.Profile{
-		User: config.UserConfig{
-			Name:       "Test User",
-			Email:      "test@example.com",
-			SigningKey: "ABCD1234",
-		},
-		URL: []config.URLConfig{
-			{
-				Pattern:   "ssh://git@github.com/",
-				InsteadOf: "https://github.com/",
-			},
-		},
-		Core: map[string]any{
-			"editor": "vim",
-		},
-		Push: map[string]any{
-			"default": "simple",
-		},
-	}
-
-	gitConfig := profileToGitConfig(profile)
-
-	// Verify user config
-	if gitConfig["user.name"] != "Test User" {
-		t.Error("Git config should contain user.name")
-	}
-
-	if gitConfig["user.email"] != "test@example.com" {
-		t.Error("Git config should contain user.email")
-	}
-
-	if gitConfig["user.signingkey"] != "ABCD1234" {
-		t.Error("Git config should contain user.signingkey")
-	}
-
-	// Verify URL rewrite
-	urlKey := `url "ssh://git@github.com/".insteadOf`
-	if gitConfig[urlKey] != "https://github.com/" {
-		t.Error("Git config should contain URL rewrite")
-	}
-
-	// Verify other sections
-	if gitConfig["core.editor"] != "vim" {
-		t.Error("Git config should contain core.editor")
-	}
-
-	if gitConfig["push.default"] != "simple" {
-		t.Error("Git config should contain push.default")
-	}
-}
-
-func TestAddSectionToConfig(t *testing.T) {
-	t.Parallel()
-
-	gitConfig := make(map[string]any)
-
-	section := map[string]any{
-		"editor":   "vim",
-		"autocrlf": "input",
-	}
-
-	addSectionToConfig(gitConfig, "core", section)
-
-	if gitConfig["core.editor"] != "vim" {
-		t.Error("Should add core.editor")
-	}
-
-	if gitConfig["core.autocrlf"] != "input" {
-		t.Error("Should add core.autocrlf")
-	}
-}
-
-func TestAddSectionToConfigNested(t *testing.T) {
-	t.Parallel()
-
-	gitConfig := make(map[string]any)
-
-	section := map[string]any{
-		"interactive": map[string]any{
-			"diffFilter": "delta --color-only",
-		},
-	}
-
-	addSectionToConfig(gitConfig, "add", section)
-
-	if gitConfig["add.interactive.diffFilter"] != "delta --color-only" {
-		t.Error("Should add nested config values")
-	}
-}
-
 func TestInitCommandExists(t *testing.T) {
 	t.Parallel()
 
@@ -492,129 +401,139 @@ func TestRootCommandMetadata(t *testing.T) {
 	}
 }
 
-func TestProfileToGitConfigAllSections(t *testing.T) {
+func TestOverlayConfig(t *testing.T) {
 	t.Parallel()
 
-	profile := &config.Profile{
-		User: config.UserConfig{
-			Name:       "Test User",
-			Email:      "test@example.com",
-			SigningKey: "KEY123",
-		},
-		HTTP: map[string]any{
-			"postBuffer": "524288000",
-		},
-		Core: map[string]any{
-			"editor": "vim",
-		},
-		Interactive: map[string]any{
-			"singleKey": "true",
-		},
-		Add: map[string]any{
-			"interactive": map[string]any{
-				"useBuiltin": "false",
-			},
-		},
-		Delta: map[string]any{
-			"navigate": "true",
-		},
-		Push: map[string]any{
-			"default": "current",
-		},
-		Merge: map[string]any{
-			"conflictStyle": "diff3",
-		},
-		Commit: map[string]any{
-			"gpgsign": "true",
-		},
-		GPG: map[string]any{
-			"program": "gpg2",
-		},
-		Pull: map[string]any{
-			"rebase": "true",
-		},
-		Rerere: map[string]any{
-			"enabled": "true",
-		},
-		Column: map[string]any{
-			"ui": "auto",
-		},
-		Branch: map[string]any{
-			"autoSetupRebase": "always",
-		},
-		Init: map[string]any{
-			"defaultBranch": "main",
-		},
+	globalConfig := map[string]any{
+		"core.editor":      "vim",
+		"core.pager":       "less",
+		"push.default":     "simple",
+		"safe.directory":   []any{"/srv/shared"},
+		"http.extraHeader": []any{"X-One: 1"},
 	}
 
-	gitConfig := profileToGitConfig(profile)
-
-	// Verify all sections are present
-	sections := []string{
-		"user.name", "user.email", "user.signingkey",
-		"http.postBuffer",
-		"core.editor",
-		"interactive.singleKey",
-		"add.interactive.useBuiltin",
-		"delta.navigate",
-		"push.default",
-		"merge.conflictStyle",
-		"commit.gpgsign",
-		"gpg.program",
-		"pull.rebase",
-		"rerere.enabled",
-		"column.ui",
-		"branch.autoSetupRebase",
-		"init.defaultBranch",
+	profileConfig := map[string]any{
+		"core.editor":      "vim",
+		"push.Default":     "current",
+		"safe.directory":   []any{"/srv/shared", "/srv/work"},
+		"http.extraHeader": []any{"X-Two: 2"},
+		"user.email":       "work@example.com",
 	}
 
-	for _, key := range sections {
-		if _, exists := gitConfig[key]; !exists {
-			t.Errorf("Git config should contain key: %s", key)
-		}
+	overlay, unset := overlayConfig(profileConfig, globalConfig)
+
+	if _, exists := overlay["core.editor"]; exists {
+		t.Error("Keys equal to the global value should be left out")
+	}
+
+	if overlay["push.Default"] != "current" || overlay["user.email"] != "work@example.com" {
+		t.Errorf("Changed and new keys should be kept, got %v", overlay)
+	}
+
+	if dirs, ok := overlay["safe.directory"].([]any); !ok || len(dirs) != 1 || dirs[0] != "/srv/work" {
+		t.Errorf("Extended lists should only keep the extra values, got %v", overlay["safe.directory"])
+	}
+
+	if headers, ok := overlay["http.extraHeader"].([]any); !ok || len(headers) != 1 || headers[0] != "X-Two: 2" {
+		t.Errorf("Replaced lists should be kept whole, got %v", overlay["http.extraHeader"])
+	}
+
+	if len(unset) != 1 || unset[0] != "core.pager" {
+		t.Errorf("Expected core.pager to be reported as unset, got %v", unset)
 	}
 }
 
-func TestProfileToGitConfigEmptySections(t *testing.T) {
+func TestCompileCommandExists(t *testing.T) {
 	t.Parallel()
 
-	profile := &config.Profile{
-		User: config.UserConfig{
-			Name:  "Test",
-			Email: "test@test.com",
-		},
-		// All other sections empty/nil
+	cmd, _, err := rootCmd.Find([]string{"compile"})
+	if err != nil || cmd.Name() != "compile" {
+		t.Errorf("compile command should be registered, got %v (%v)", cmd, err)
 	}
+}
 
-	gitConfig := profileToGitConfig(profile)
+// executeCommand runs the CLI with args and returns its standard output and
+// standard error. It shares the command tree, so callers cannot be parallel.
+func executeCommand(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
 
-	// Should have user config
-	if gitConfig["user.name"] != "Test" {
-		t.Error("Should have user.name")
-	}
+	var stdout, stderr bytes.Buffer
+
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs(args)
+
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	err := rootCmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
+
+func TestExportOutputExcludesWarnings(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	gitConfigFile := filepath.Join(dir, "gitconfig")
+
+	// usr is a typo and home has no email, both only warnings
+	source := `version: 1
+global: {}
+profiles:
+  work:
+    user:
+      name: Jane
+      email: jane@work.example
+    usr:
+      name: typo
+  home:
+    user:
+      name: Jane
+`
+	if err := os.WriteFile(configFile, []byte(source), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	for _, format := range []string{exportFormatGitConfig, exportFormatBundle} {
+		stdout, stderr, err := executeCommand(t,
+			"--config", configFile, "--gitconfig", gitConfigFile, "export", "work", "--format", format)
+		if err != nil {
+			t.Fatalf("export --format %s failed: %v", format, err)
+		}
 
-	// Other sections should not add keys
-	if val, exists := gitConfig["http.something"]; exists {
-		t.Errorf("Should not have http keys, got: %v", val)
+		if strings.Contains(stdout, "⚠") || !strings.Contains(stdout, "jane@work.example") {
+			t.Errorf("Expected only the %s on standard output, got:\n%s", format, stdout)
+		}
+
+		if !strings.Contains(stderr, "section 'usr'") || !strings.Contains(stderr, "profile 'home'") {
+			t.Errorf("Expected the warnings on standard error, got:\n%s", stderr)
+		}
 	}
 }
 
-func TestAddSectionToConfigRecursiveDeepNesting(t *testing.T) {
-	t.Parallel()
+func TestMigrateDryRunOutput(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
 
-	gitConfig := make(map[string]any)
+	source := "# My profiles\nglobal: {}\nprofiles: {}\n"
+	if err := os.WriteFile(configFile, []byte(source), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
 
-	values := map[string]any{
-		"level1": map[string]any{
-			"level2": map[string]any{
-				"level3": "deepvalue",
-			},
-		},
+	stdout, stderr, err := executeCommand(t,
+		"--config", configFile, "--gitconfig", filepath.Join(dir, "gitconfig"), "migrate", "--dry-run")
+	if err != nil {
+		t.Fatalf("migrate --dry-run failed: %v", err)
 	}
 
-	addSectionToConfigRecursive(gitConfig, "section", values)
+	if want := "# My profiles\nversion: 1\nglobal: {}\nprofiles: {}\n"; stdout != want {
+		t.Errorf("Expected only the upgraded file on standard output, got:\n%s", stdout)
+	}
 
-	if gitConfig["section.level1.level2.level3"] != "deepvalue" {
-		t.Error("Should handle deep nesting")
+	if !strings.Contains(stderr, "0 → 1") {
+		t.Errorf("Expected the steps on standard error, got:\n%s", stderr)
 	}
 }
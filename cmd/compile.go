@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+const profileFileSuffix = ".gitconfig"
+
+var compileCmd = &cobra.Command{
+	Use:   "compile",
+	Short: "Generate an includeIf-based git config from profile rules",
+	Long: `Write one git config file per profile that has directories or remotes rules,
+and reference them from the global git config with includeIf entries.
+
+Git then selects the profile of each repository by itself: a repository below
+one of the profile's directories, or with a remote URL matching one of its
+remotes patterns, gets the profile's settings on top of the global ones.
+When several rules match, the profile listed last (alphabetically) wins.`,
+	Args: cobra.NoArgs,
+	RunE: runCompile,
+}
+
+// runCompile handles the 'compile' command.
+func runCompile(cmd *cobra.Command, args []string) error {
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	mode, err := git.ParseMode(cfg.Settings.Mode)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Invalid settings: %v", err))
+
+		return errors.Wrap(err, "invalid write mode")
+	}
+
+	symlinks, err := symlinkPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSymlink(paths.GitConfigFile, symlinks); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(paths.ProfilesDir, 0o755); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to create profiles directory: %v", err))
+
+		return errors.Wrap(err, "failed to create profiles directory")
+	}
+
+	ui.PrintHeader("Compiling Profiles")
+
+	globalConfig := cfg.MergeGlobal().GitConfig()
+
+	var includes []git.Include
+
+	written := make(map[string]bool)
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		profile, _ := cfg.GetProfile(name)
+		if profile == nil || !profile.HasRules() {
+			continue
+		}
+
+		merged, err := cfg.Merge(name)
+		if err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to merge profile '%s': %v", name, err))
+
+			return errors.Wrapf(err, "failed to merge profile '%s'", name)
+		}
+
+		overlay, unset := overlayConfig(merged.GitConfig(), globalConfig)
+		for _, key := range unset {
+			ui.PrintWarning(fmt.Sprintf("Profile '%s' unsets %s, which an included file cannot undo", name, key))
+		}
+
+		profileFile := filepath.Join(paths.ProfilesDir, name+profileFileSuffix)
+		if err := git.NewGit(profileFile).WriteConfig(overlay, cfg.GlobalSections()...); err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to write profile config: %v", err))
+
+			return errors.Wrapf(err, "failed to write config of profile '%s'", name)
+		}
+
+		written[filepath.Base(profileFile)] = true
+
+		for _, condition := range profile.IncludeConditions() {
+			includes = append(includes, git.Include{Condition: condition, Path: profileFile})
+			ui.PrintInfo(fmt.Sprintf("%s → %s", condition, name))
+		}
+	}
+
+	if len(written) == 0 {
+		ui.PrintWarning("No profile defines directories or remotes rules")
+	}
+
+	removeStaleProfileFiles(paths.ProfilesDir, written)
+
+	g := git.NewGit(
+		paths.GitConfigFile,
+		git.WithMode(mode),
+		git.WithIncludePath(paths.ManagedConfigFile),
+		git.WithSymlinkPolicy(symlinks),
+	)
+
+	backupGitConfig(cfg, paths, paths.GitConfigFile, cfg.Current)
+
+	if err := g.WriteConfigWithIncludes(globalConfig, includes, cfg.GlobalSections()...); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to write git config: %v", err))
+
+		return errors.Wrap(err, "failed to write git config")
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Compiled %d profile(s) into %s", len(written), paths.GitConfigFile))
+
+	return nil
+}
+
+// overlayConfig returns the keys of profileConfig that differ from
+// globalConfig, to be written to a file included on top of the global config.
+// A list extending the global list only keeps the extra values, since git
+// accumulates repeated keys. Global keys missing from profileConfig are
+// returned as unset: an included file has no way to remove them.
+func overlayConfig(profileConfig, globalConfig map[string]any) (map[string]any, []string) {
+	global := make(map[string]any, len(globalConfig))
+	for key, value := range globalConfig {
+		global[git.CanonicalKey(key)] = value
+	}
+
+	overlay := make(map[string]any)
+	seen := make(map[string]bool)
+
+	for key, value := range profileConfig {
+		canonical := git.CanonicalKey(key)
+		seen[canonical] = true
+
+		inherited, exists := global[canonical]
+
+		switch {
+		case !exists:
+			overlay[key] = value
+		case sameValue(inherited, value):
+			// Already set by the global config
+		default:
+			if extra, ok := listSuffix(inherited, value); ok {
+				overlay[key] = extra
+			} else {
+				overlay[key] = value
+			}
+		}
+	}
+
+	var unset []string
+
+	for _, key := range slices.Sorted(maps.Keys(global)) {
+		if !seen[key] {
+			unset = append(unset, key)
+		}
+	}
+
+	return overlay, unset
+}
+
+// listSuffix returns the values of list following base when list starts with
+// every value of base.
+func listSuffix(base, list any) ([]any, bool) {
+	baseValues, ok := base.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	values, ok := list.([]any)
+	if !ok || len(values) <= len(baseValues) || !slices.Equal(values[:len(baseValues)], baseValues) {
+		return nil, false
+	}
+
+	return values[len(baseValues):], true
+}
+
+// sameValue reports whether two git config values, scalars or lists of
+// scalars, are equal.
+func sameValue(first, second any) bool {
+	firstList, firstIsList := first.([]any)
+	secondList, secondIsList := second.([]any)
+
+	if firstIsList || secondIsList {
+		return firstIsList && secondIsList && slices.Equal(firstList, secondList)
+	}
+
+	return first == second
+}
+
+// removeStaleProfileFiles deletes compiled profile files that were not
+// written by the current run, such as those of removed profiles.
+func removeStaleProfileFiles(dir string, written map[string]bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, profileFileSuffix) || written[name] {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to remove stale profile config %s: %v", name, err))
+		} else {
+			ui.PrintInfo("Removed stale profile config " + name)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(compileCmd)
+}
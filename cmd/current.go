@@ -17,16 +17,17 @@ var currentCmd = &cobra.Command{
 }
 
 // runCurrent handles the 'current' command to show the currently active profile.
-// It compares git configuration with saved profiles to determine which is active.
+// The active profile is read from the marker switch writes to the git config,
+// falling back to matching the configured identity against the profiles.
 func runCurrent(cmd *cobra.Command, args []string) error {
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
 
 		return errors.Wrap(err, "failed to get paths")
 	}
 
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 
@@ -39,7 +40,7 @@ func runCurrent(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	profile, err := cfg.GetProfile(cfg.Current)
+	profile, err := cfg.Merge(cfg.Current)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Active profile not found: %v", err))
 
@@ -51,6 +52,24 @@ func runCurrent(cmd *cobra.Command, args []string) error {
 	ui.PrintInfo("Name: " + profile.User.Name)
 	ui.PrintInfo("Email: " + profile.User.Email)
 
+	// The state file only describes the live config when it was switched to that profile
+	if state, err := config.LoadState(paths.StateFile); err == nil && state.Current == cfg.Current {
+		if !state.SwitchedAt.IsZero() {
+			ui.PrintInfo("Switched: " + state.SwitchedAt.Format("2006-01-02 15:04:05"))
+		}
+
+		if state.Previous != "" {
+			ui.PrintInfo("Previous: " + state.Previous)
+		}
+	}
+
+	if cfg.Modified {
+		ui.PrintWarning(fmt.Sprintf(
+			"The git config was modified since switching; run 'git-context switch %s' to restore it",
+			cfg.Current,
+		))
+	}
+
 	return nil
 }
 
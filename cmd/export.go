@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// Export formats.
+const (
+	exportFormatGitConfig = "gitconfig"
+	exportFormatBundle    = "bundle"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [profile-name]",
+	Short: "Print a profile as a git config file or a portable bundle",
+	Long: `Print a profile to standard output.
+
+With --format gitconfig (the default), the fully merged profile is printed as a
+git config file, which can be used with GIT_CONFIG_GLOBAL or include.path.
+
+With --format bundle, the profile is printed as YAML together with the global
+entries it depends on, and the profiles it extends flattened into it. Load it
+on another machine with 'git-context import --bundle <file>'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+var exportFormat string
+
+// runExport handles the 'export' command.
+func runExport(cmd *cobra.Command, args []string) error {
+	// Standard output is kept for the data, which is often redirected to a file
+	status := cmd.ErrOrStderr()
+
+	profileName := args[0]
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	if _, err := cfg.GetProfile(profileName); err != nil {
+		ui.FprintError(status, fmt.Sprintf("Profile not found: %v", err))
+
+		return errors.Wrap(err, "profile not found")
+	}
+
+	switch exportFormat {
+	case exportFormatGitConfig:
+		merged, err := cfg.Merge(profileName)
+		if err != nil {
+			ui.FprintError(status, fmt.Sprintf("Failed to merge configurations: %v", err))
+
+			return errors.Wrap(err, "failed to merge configurations")
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), git.FormatConfig(merged.GitConfig(), cfg.GlobalSections()...))
+	case exportFormatBundle:
+		bundle, err := cfg.NewBundle(profileName)
+		if err != nil {
+			ui.FprintError(status, fmt.Sprintf("Failed to export profile: %v", err))
+
+			return errors.Wrap(err, "failed to export profile")
+		}
+
+		data, err := yaml.Marshal(bundle)
+		if err != nil {
+			ui.FprintError(status, fmt.Sprintf("Failed to export profile: %v", err))
+
+			return errors.Wrap(err, "failed to marshal bundle")
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), string(data))
+	default:
+		ui.FprintError(status, fmt.Sprintf("Unknown format '%s', expected %s or %s",
+			exportFormat, exportFormatGitConfig, exportFormatBundle))
+
+		return errors.WithStack(errors.Newf("unknown export format '%s'", exportFormat))
+	}
+
+	return nil
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", exportFormatGitConfig,
+		"output format: gitconfig or bundle")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [profile-name]",
+	Short: "Create a profile from an existing git config",
+	Long: `Read a git config file, the global one by default, and store its keys as a new
+profile in config.yaml.
+
+With --includeif, the includeIf directives of the file are followed instead:
+each included file becomes a profile named after it (~/.gitconfig-work gives
+"work"), whose gitdir and remote URL conditions become its directories and
+remotes rules. The keys of the file itself go into the named profile, or into
+global when no name is given.
+
+With --bundle, a profile exported by 'git-context export --format bundle' is
+loaded, under its own name unless another is given. Its global entries that
+global lacks or sets differently are kept in the profile; global is unchanged.
+
+With --extract-common, keys set to the same value by every profile are then
+moved into global. Entries that config.yaml cannot hold, such as include
+directives, are listed and left out.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: runImport,
+}
+
+var (
+	importFrom          string
+	importIncludeIf     bool
+	importBundle        string
+	importExtractCommon bool
+)
+
+// runImport handles the 'import' command.
+func runImport(cmd *cobra.Command, args []string) error {
+	var profileName string
+	if len(args) > 0 {
+		profileName = args[0]
+	}
+
+	if profileName == "" && !importIncludeIf && importBundle == "" {
+		ui.PrintError("A profile name is required unless --includeif or --bundle is given")
+
+		return errors.New("missing profile name")
+	}
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	if importBundle != "" {
+		return importFromBundle(cfg, paths, profileName)
+	}
+
+	source := importFrom
+	if source == "" {
+		source = paths.GitConfigFile
+	}
+
+	file, err := git.ParseFile(source)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read %s: %v", source, err))
+
+		return errors.Wrapf(err, "failed to read %s", source)
+	}
+
+	existing := slices.Collect(maps.Keys(cfg.Profiles))
+
+	var skipped []string
+	if importIncludeIf {
+		skipped, err = cfg.ImportIncludes(profileName, source, file)
+	} else {
+		skipped, err = cfg.ImportProfile(profileName, file)
+	}
+
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to import profile: %v", err))
+
+		return errors.Wrap(err, "failed to import profile")
+	}
+
+	for _, entry := range skipped {
+		ui.PrintWarning("Not imported: " + entry)
+	}
+
+	if err := saveImport(cfg, paths); err != nil {
+		return err
+	}
+
+	if profileName == "" {
+		ui.PrintSuccess("Imported " + source)
+	} else {
+		ui.PrintSuccess(fmt.Sprintf("Profile '%s' imported from %s", profileName, source))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		if importIncludeIf && !slices.Contains(existing, name) && name != profileName {
+			ui.PrintInfo(fmt.Sprintf("Created profile '%s' for %s", name,
+				strings.Join(cfg.Profiles[name].IncludeConditions(), ", ")))
+		}
+	}
+
+	if importIncludeIf {
+		ui.PrintInfo("Run 'git-context compile' to replace the includeIf directives with generated ones")
+	}
+
+	return nil
+}
+
+// importFromBundle loads a bundle as profileName, or under the bundled name,
+// asking whether to replace a profile of the same name or pick another name.
+func importFromBundle(cfg *config.Config, paths *config.Paths, profileName string) error {
+	bundle, err := config.LoadBundle(importBundle)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load bundle: %v", err))
+
+		return errors.Wrap(err, "failed to load bundle")
+	}
+
+	if profileName == "" {
+		profileName = bundle.ProfileName()
+	}
+
+	if _, exists := cfg.Profiles[profileName]; exists {
+		replace, err := ui.PromptConfirm(fmt.Sprintf("Profile '%s' already exists, replace it?", profileName))
+		if err != nil {
+			ui.PrintWarning("Import canceled")
+
+			return errors.Wrap(err, "failed to confirm replacement")
+		}
+
+		if !replace {
+			profileName, err = ui.PromptText("New profile name", "")
+			if err != nil {
+				ui.PrintWarning("Import canceled")
+
+				return errors.Wrap(err, "failed to read profile name")
+			}
+
+			if _, exists := cfg.Profiles[profileName]; exists || profileName == "" {
+				ui.PrintError(fmt.Sprintf("Profile '%s' already exists", profileName))
+
+				return errors.WithStack(errors.Newf("profile '%s' already exists", profileName))
+			}
+		}
+	}
+
+	notes, err := cfg.ImportBundle(bundle, profileName)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to import bundle: %v", err))
+
+		return errors.Wrap(err, "failed to import bundle")
+	}
+
+	for _, note := range notes {
+		ui.PrintInfo(note)
+	}
+
+	if err := saveImport(cfg, paths); err != nil {
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Profile '%s' imported from %s", profileName, importBundle))
+
+	return nil
+}
+
+// saveImport moves common keys into global when requested, then saves the config.
+func saveImport(cfg *config.Config, paths *config.Paths) error {
+	if importExtractCommon {
+		moved, err := cfg.ExtractCommon()
+		if err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to extract common keys: %v", err))
+
+			return errors.Wrap(err, "failed to extract common keys")
+		}
+
+		if len(moved) > 0 {
+			ui.PrintInfo("Moved to global: " + strings.Join(moved, ", "))
+		}
+	}
+
+	if err := cfg.SaveConfig(paths.ConfigFile); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to save config: %v", err))
+
+		return errors.Wrap(err, "failed to save config")
+	}
+
+	return nil
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFrom, "from", "", "git config file to import (default: the global git config)")
+	importCmd.Flags().BoolVar(&importIncludeIf, "includeif", false,
+		"create a profile from each file included with includeIf")
+	importCmd.Flags().StringVar(&importBundle, "bundle", "", "load a bundle written by 'export --format bundle'")
+	importCmd.Flags().BoolVar(&importExtractCommon, "extract-common", false,
+		"move keys shared by every profile into global")
+	importCmd.MarkFlagsMutuallyExclusive("bundle", "from")
+	importCmd.MarkFlagsMutuallyExclusive("bundle", "includeif")
+	rootCmd.AddCommand(importCmd)
+}
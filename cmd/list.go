@@ -20,14 +20,14 @@ var listCmd = &cobra.Command{
 // runList handles the 'list' command to display all saved profiles.
 // It shows a formatted table with profile names, emails, and signing key status.
 func runList(cmd *cobra.Command, args []string) error {
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
 
 		return errors.Wrap(err, "failed to get paths")
 	}
 
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 
@@ -50,6 +50,9 @@ func runList(cmd *cobra.Command, args []string) error {
 		status := ""
 		if profile == cfg.Current {
 			status = "● (active)"
+			if cfg.Modified {
+				status = "● (modified)"
+			}
 		}
 
 		p, _ := cfg.GetProfile(profile)
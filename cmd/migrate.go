@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade config.yaml to the current format",
+	Long: `Upgrade config.yaml from an older format version to the current one.
+
+Other commands upgrade an older config.yaml in memory and write the new format
+the next time they save it; migrate does so right away. Either way the
+original file is kept as config.yaml.v<version>.bak, and only the entries a
+step changes are rewritten.
+
+With --dry-run the upgraded file is printed to standard output instead of
+written, and the steps to standard error.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+var migrateDryRun bool
+
+// runMigrate handles the 'migrate' command.
+func runMigrate(cmd *cobra.Command, args []string) error {
+	// Only the upgraded file of --dry-run goes to standard output
+	status := cmd.ErrOrStderr()
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(paths.ConfigFile)
+	if err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to read config: %v", err))
+
+		return errors.Wrap(err, "failed to read config")
+	}
+
+	migration, err := config.Migrate(data)
+	if err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to migrate config: %v", err))
+
+		return errors.Wrap(err, "failed to migrate config")
+	}
+
+	if !migration.Needed() {
+		ui.FprintSuccess(status, fmt.Sprintf("%s already uses version %d", paths.ConfigFile, max(migration.From, config.ConfigVersion)))
+
+		return nil
+	}
+
+	ui.FprintHeader(status, fmt.Sprintf("Migrating %s from version %d to %d", paths.ConfigFile, migration.From, config.ConfigVersion))
+
+	for _, step := range migration.Steps {
+		ui.FprintInfo(status, step)
+	}
+
+	if migrateDryRun {
+		fmt.Fprint(cmd.OutOrStdout(), string(migration.Data))
+
+		return nil
+	}
+
+	if err := migration.Save(paths.ConfigFile); err != nil {
+		ui.FprintError(status, fmt.Sprintf("Failed to save config: %v", err))
+
+		return errors.Wrap(err, "failed to save config")
+	}
+
+	ui.FprintSuccess(status, "Migrated "+paths.ConfigFile)
+	ui.FprintInfo(status, "Original kept as "+migration.BackupFile(paths.ConfigFile))
+
+	return nil
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print the upgraded config instead of writing it")
+	rootCmd.AddCommand(migrateCmd)
+}
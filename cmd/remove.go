@@ -22,14 +22,20 @@ var removeCmd = &cobra.Command{
 func runRemove(cmd *cobra.Command, args []string) error {
 	profileName := args[0]
 
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
 
 		return errors.Wrap(err, "failed to get paths")
 	}
 
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 
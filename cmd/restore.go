@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [id]",
+	Short: "Restore a git config backup",
+	Long: `Restore a backup listed by 'git-context backups', the latest one by default.
+
+The keys the restore would change are shown and confirmed first. The current
+content is backed up as well, so a restore can be undone.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRestore,
+}
+
+// runRestore handles the 'restore' command.
+func runRestore(cmd *cobra.Command, args []string) error {
+	var id string
+	if len(args) > 0 {
+		id = args[0]
+	}
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	store := backupStore(cfg, paths)
+
+	backup, err := store.Find(id)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Backup not found: %v", err))
+
+		return errors.Wrap(err, "backup not found")
+	}
+
+	symlinks, err := symlinkPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSymlink(backup.Source, symlinks); err != nil {
+		return err
+	}
+
+	ui.PrintHeader(fmt.Sprintf("Restoring %s from %s", backup.Source, backup.Created.Format("2006-01-02 15:04:05")))
+
+	changes, err := restoreChanges(backup)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to compare backup: %v", err))
+
+		return errors.Wrap(err, "failed to compare backup")
+	}
+
+	if len(changes) == 0 {
+		ui.PrintInfo("The backup holds the same keys as the current file")
+	} else {
+		rows := make([][]string, len(changes))
+		for i, change := range changes {
+			rows[i] = []string{
+				change.Key,
+				strings.Join(change.Live, ", "),
+				strings.Join(change.Expected, ", "),
+			}
+		}
+
+		ui.PrintTable([]string{"Key", "Current", "Backup"}, rows)
+		fmt.Println()
+	}
+
+	confirm, err := ui.PromptConfirm(fmt.Sprintf("Restore backup %s?", backup.ID))
+	if err != nil {
+		ui.PrintWarning("Restore canceled")
+
+		return errors.Wrap(err, "failed to confirm restore")
+	}
+
+	if !confirm {
+		ui.PrintWarning("Restore canceled")
+
+		return nil
+	}
+
+	// The backup of the current content records the profile active in it
+	active := cfg.Current
+	if backup.Source != paths.GitConfigFile {
+		active = ""
+	}
+
+	undo, err := store.Restore(backup, active)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to restore backup: %v", err))
+
+		return errors.Wrap(err, "failed to restore backup")
+	}
+
+	if undo != nil {
+		ui.PrintInfo(fmt.Sprintf("Backed up %s as %s", backup.Source, undo.ID))
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Restored %s from backup %s", backup.Source, backup.ID))
+
+	return nil
+}
+
+// restoreChanges returns the keys that differ between the current content of
+// the backed up file and the backup. Expected holds the backup values.
+func restoreChanges(backup *config.Backup) ([]git.Change, error) {
+	saved, err := fileEntries(backup.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := fileEntries(backup.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	return git.Diff(saved, current, current), nil
+}
+
+// fileEntries returns the values set in a git config file, without following
+// includes. A missing file has no values.
+func fileEntries(path string) (git.Values, error) {
+	values := make(git.Values)
+
+	file, err := git.ParseFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+
+		return nil, err
+	}
+
+	for _, entry := range file.Entries() {
+		values[entry.Key] = append(values[entry.Key], entry.Value)
+	}
+
+	return values, nil
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+}
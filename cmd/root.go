@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/fsutil"
 	"github.com/aanogueira/git-context/internal/ui"
 	"github.com/cockroachdb/errors"
 	"github.com/spf13/cobra"
@@ -15,10 +17,19 @@ var rootCmd = &cobra.Command{
 	Long: `Git Context is a CLI tool that helps you manage multiple git configuration profiles.
 
 Switch between different git identities (work, personal, school, etc.) with a single command.
-Profiles are stored in ~/.config/git-context/config.yaml`,
-	Version: "1.0.0",
+Profiles are stored in $XDG_CONFIG_HOME/git-context/config.yaml, ~/.config by
+default. --config and --gitconfig, or GIT_CONTEXT_CONFIG and
+GIT_CONTEXT_GITCONFIG, point git-context at other files.`,
+	Version:           "1.0.0",
+	PersistentPreRunE: checkConfig,
 }
 
+var (
+	strictConfig      bool   // Makes the warnings of config.yaml fatal
+	configFileFlag    string // Overrides the location of config.yaml
+	gitConfigFileFlag string // Overrides the global git config to manage
+)
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize git-context configuration",
@@ -29,15 +40,21 @@ var initCmd = &cobra.Command{
 // runInit handles the 'init' command to initialize the configuration.
 // It creates the config directory and file if they don't exist.
 func runInit(cmd *cobra.Command, args []string) error {
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to initialize paths: %v", err))
 
 		return errors.Wrap(err, "failed to initialize paths")
 	}
 
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Check if config already exists
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load existing config: %v", err))
 
@@ -69,6 +86,52 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// lockTimeout is how long a command waits for another git-context process.
+const lockTimeout = 10 * time.Second
+
+// lockConfig takes the lock shared by the git-context processes that modify
+// files. It is held from loading config.yaml until the last write, so a
+// concurrent switch, for example from a shell hook, cannot interleave.
+func lockConfig(paths *config.Paths) (func(), error) {
+	lock, err := fsutil.Acquire(paths.LockFile, lockTimeout)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to lock configuration: %v", err))
+
+		return nil, errors.Wrap(err, "failed to lock configuration")
+	}
+
+	return func() {
+		if err := lock.Release(); err != nil {
+			ui.PrintWarning(fmt.Sprintf("Failed to release lock: %v", err))
+		}
+	}, nil
+}
+
+// checkConfig prints the warnings of config.yaml before a command runs, and
+// with --strict stops it. Errors are reported when the command loads it.
+func checkConfig(cmd *cobra.Command, args []string) error {
+	if cmd == validateCmd {
+		return nil
+	}
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	// A file that cannot be read is reported once the command loads it
+	diagnostics, _ := config.ValidateFile(paths.ConfigFile)
+
+	warnings := diagnostics.Warnings()
+	printDiagnostics(cmd.ErrOrStderr(), paths.ConfigFile, warnings)
+
+	if strictConfig && len(warnings) > 0 {
+		return errors.WithStack(errors.Newf("%s has %d warning(s), see 'git-context validate'", paths.ConfigFile, len(warnings)))
+	}
+
+	return nil
+}
+
 // Execute is the main entry point for the CLI.
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
@@ -79,5 +142,10 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&configFileFlag, "config", "",
+		"config file (default: $"+config.ConfigFileEnv+" or $XDG_CONFIG_HOME/git-context/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&gitConfigFileFlag, "gitconfig", "",
+		"global git config to manage (default: $"+config.GitConfigFileEnv+", $GIT_CONFIG_GLOBAL or git's own lookup)")
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict", false, "treat warnings about config.yaml as errors")
 	rootCmd.AddCommand(initCmd)
 }
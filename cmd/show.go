@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/aanogueira/git-context/internal/config"
 	"github.com/aanogueira/git-context/internal/ui"
@@ -12,24 +15,29 @@ import (
 var showCmd = &cobra.Command{
 	Use:   "show [profile-name]",
 	Short: "Show profile details",
-	Long:  `Display the configuration details for a specific profile.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runShow,
+	Long: `Display the configuration details for a specific profile.
+
+With --origins, the resolved git configuration is listed instead, along with
+the layer (global or a profile from the extends chain) that supplied each value.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShow,
 }
 
+var showOrigins bool
+
 // runShow handles the 'show' command to display details of a specific profile.
 // It presents all configured values including user info, signing keys, and URL rewrites.
 func runShow(cmd *cobra.Command, args []string) error {
 	profileName := args[0]
 
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
 
 		return errors.Wrap(err, "failed to get paths")
 	}
 
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 
@@ -43,8 +51,16 @@ func runShow(cmd *cobra.Command, args []string) error {
 		return errors.Wrap(err, "failed to get profile")
 	}
 
+	if showOrigins {
+		return showResolved(cfg, profileName)
+	}
+
 	ui.PrintHeader("Profile: " + profileName)
 
+	if len(profile.Extends) > 0 {
+		ui.PrintInfo("Extends: " + strings.Join(profile.Extends, ", "))
+	}
+
 	if profile.User.Name != "" {
 		ui.PrintInfo("User Name: " + profile.User.Name)
 	}
@@ -69,6 +85,48 @@ func runShow(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// showResolved prints every git key the profile resolves to and where its value comes from.
+func showResolved(cfg *config.Config, profileName string) error {
+	merged, origins, err := cfg.MergeWithOrigins(profileName)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to merge configurations: %v", err))
+
+		return errors.Wrap(err, "failed to merge configurations")
+	}
+
+	chain, _ := cfg.Chain(profileName)
+
+	ui.PrintHeader("Profile: " + profileName)
+	ui.PrintInfo("Resolved from: " + strings.Join(append([]string{config.GlobalLayer}, chain...), " → "))
+
+	gitConfig := merged.GitConfig()
+
+	rows := make([][]string, 0, len(gitConfig))
+	for _, key := range slices.Sorted(maps.Keys(gitConfig)) {
+		rows = append(rows, []string{key, formatShowValue(gitConfig[key]), origins.Of(key)})
+	}
+
+	fmt.Println()
+	ui.PrintTable([]string{"Key", "Value", "From"}, rows)
+
+	return nil
+}
+
+// formatShowValue renders a git config value for display.
+func formatShowValue(value any) string {
+	if values, ok := value.([]any); ok {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprintf("%v", v)
+		}
+
+		return strings.Join(parts, ", ")
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
 func init() {
+	showCmd.Flags().BoolVar(&showOrigins, "origins", false, "show the resolved config and where each value comes from")
 	rootCmd.AddCommand(showCmd)
 }
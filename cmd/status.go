@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how the git config drifted from the active profile",
+	Long: `Compare the live global git config with the active profile and list the keys
+that were added, changed or removed since the last switch.
+
+Exits with a non-zero status when the config has drifted, so it can run in a
+shell startup file or in CI. With the block and include write modes, keys set
+outside the region managed by git-context are not reported as added.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+	// Drift is reported by the command itself, only the exit status matters
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+// runStatus handles the 'status' command.
+func runStatus(cmd *cobra.Command, args []string) error {
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	if cfg.Current == "" {
+		ui.PrintWarning("No active profile set")
+
+		return nil
+	}
+
+	changes, err := configDrift(cfg, paths, cfg.Current)
+	if err != nil {
+		return err
+	}
+
+	ui.PrintHeader("Status: " + cfg.Current)
+
+	if len(changes) == 0 {
+		ui.PrintSuccess(fmt.Sprintf("Git config matches profile '%s'", cfg.Current))
+
+		return nil
+	}
+
+	rows := make([][]string, len(changes))
+	for i, change := range changes {
+		rows[i] = []string{
+			change.Key,
+			string(change.Kind),
+			strings.Join(change.Expected, ", "),
+			strings.Join(change.Live, ", "),
+		}
+	}
+
+	ui.PrintTable([]string{"Key", "Change", "Profile", "Live"}, rows)
+	fmt.Println()
+	ui.PrintWarning(fmt.Sprintf("%d key(s) drifted from profile '%s'", len(changes), cfg.Current))
+
+	return errors.WithStack(errors.Newf("git config has drifted from profile '%s'", cfg.Current))
+}
+
+// configDrift returns the differences between the live global git config and
+// what switching to profileName writes.
+func configDrift(cfg *config.Config, paths *config.Paths, profileName string) ([]git.Change, error) {
+	mode, err := git.ParseMode(cfg.Settings.Mode)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Invalid settings: %v", err))
+
+		return nil, errors.Wrap(err, "invalid write mode")
+	}
+
+	merged, err := cfg.Merge(profileName)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to merge configurations: %v", err))
+
+		return nil, errors.Wrap(err, "failed to merge configurations")
+	}
+
+	gitConfig := merged.GitConfig()
+	gitConfig[git.ProfileKey] = profileName
+
+	g := git.NewGit(
+		paths.GitConfigFile,
+		git.WithMode(mode),
+		git.WithIncludePath(paths.ManagedConfigFile),
+	)
+
+	live, err := g.ReadValues()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read git config: %v", err))
+
+		return nil, errors.Wrap(err, "failed to read git config")
+	}
+
+	managed, err := g.ReadManagedValues()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read git config: %v", err))
+
+		return nil, errors.Wrap(err, "failed to read managed git config")
+	}
+
+	return git.Diff(git.ConfigValues(gitConfig), live, managed), nil
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aanogueira/git-context/internal/config"
 	"github.com/aanogueira/git-context/internal/git"
@@ -13,31 +14,56 @@ import (
 var switchCmd = &cobra.Command{
 	Use:   "switch [profile-name]",
 	Short: "Switch to a different profile",
-	Long:  `Switch the active git configuration to a different profile.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runSwitch,
+	Long: `Switch the active git configuration to a different profile.
+
+By default the global git config is written. With --local the profile is
+written to the current repository's config instead, and with --worktree to the
+config of the current worktree only. Repository settings such as remotes and
+branches are left untouched.
+
+config.yaml is never written: the active and previous profiles and the time
+of the switch are recorded in the state file next to it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSwitch,
 }
 
+var (
+	switchLocal    bool
+	switchWorktree bool
+)
+
 func runSwitch(cmd *cobra.Command, args []string) error {
 	profileName := args[0]
 
-	paths, err := config.NewPaths()
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
 
 		return errors.Wrap(err, "failed to get paths")
 	}
 
-	cfg, err := config.LoadConfig(paths.ConfigFile)
+	unlock, err := lockConfig(paths)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 
 		return errors.Wrap(err, "failed to load config")
 	}
 
-	// Check if profile exists
-	profile, err := cfg.GetProfile(profileName)
+	state, err := config.LoadState(paths.StateFile)
 	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load state: %v", err))
+
+		return errors.Wrap(err, "failed to load state")
+	}
+
+	// Check if profile exists
+	if _, err := cfg.GetProfile(profileName); err != nil {
 		ui.PrintError(fmt.Sprintf("Profile not found: %v", err))
 
 		return errors.Wrap(err, "profile not found")
@@ -45,14 +71,21 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 
 	ui.PrintHeader("Switching to Profile: " + profileName)
 
-	// Create Git instance
-	g := git.NewGit(paths.GitConfigFile)
+	g, configPath, err := switchTarget(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	// Keys that differ only in case collapse to one git key
+	warnings, err := cfg.KeyCollisions(profileName)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to check keys: %v", err))
 
-	// Backup current config
-	if err := g.BackupConfig(paths.GitConfigBackup); err != nil {
-		ui.PrintWarning(fmt.Sprintf("Failed to backup git config: %v", err))
-	} else {
-		ui.PrintInfo("Backed up git config to " + paths.GitConfigBackup)
+		return errors.Wrap(err, "failed to check keys")
+	}
+
+	for _, warning := range warnings {
+		ui.PrintWarning(warning)
 	}
 
 	// Build the merged configuration
@@ -64,92 +97,133 @@ func runSwitch(cmd *cobra.Command, args []string) error {
 	}
 
 	// Convert profile to git config format and write
-	gitConfig := profileToGitConfig(mergedProfile)
-	if err := g.WriteConfig(gitConfig); err != nil {
+	gitConfig := mergedProfile.GitConfig()
+
+	// Record the profile, so profiles sharing an identity can be told apart
+	gitConfig[git.ProfileKey] = profileName
+
+	// Backup current config right before writing, along with the profile it holds
+	active := cfg.Current
+	if switchLocal || switchWorktree {
+		active, _, _ = git.RepoProfile(".")
+	}
+
+	backupGitConfig(cfg, paths, configPath, active)
+
+	if err := g.WriteConfig(gitConfig, cfg.GlobalSections()...); err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to write git config: %v", err))
 
 		return errors.Wrap(err, "failed to write git config")
 	}
 
-	// Update current profile
-	cfg.Current = profileName
-	if err := cfg.SaveConfig(paths.ConfigFile); err != nil {
-		ui.PrintError(fmt.Sprintf("Failed to save config: %v", err))
+	if switchLocal || switchWorktree {
+		ui.PrintSuccess(fmt.Sprintf("Pinned repository to profile '%s'", profileName))
+		ui.PrintInfo(fmt.Sprintf("User: %s <%s>", mergedProfile.User.Name, mergedProfile.User.Email))
 
-		return errors.Wrap(err, "failed to save config")
+		return nil
+	}
+
+	// Record the switch in the state file, config.yaml is left as authored
+	state.RecordSwitch(cfg.Current, profileName, time.Now().Truncate(time.Second))
+
+	if err := state.SaveState(paths.StateFile); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to save state: %v", err))
+
+		return errors.Wrap(err, "failed to save state")
 	}
 
 	ui.PrintSuccess(fmt.Sprintf("Switched to profile '%s'", profileName))
-	ui.PrintInfo(fmt.Sprintf("User: %s <%s>", profile.User.Name, profile.User.Email))
+	ui.PrintInfo(fmt.Sprintf("User: %s <%s>", mergedProfile.User.Name, mergedProfile.User.Email))
 
 	return nil
 }
 
-// profileToGitConfig converts a Profile to a git configuration map.
-// It maps profile fields to git config keys (user.name, user.email, etc.).
-func profileToGitConfig(profile *config.Profile) map[string]any {
-	gitConfig := make(map[string]any)
-
-	// User section
-	if profile.User.Name != "" {
-		gitConfig["user.name"] = profile.User.Name
+// switchTarget returns the Git instance writing the config selected by the
+// command flags, along with the path of that config.
+func switchTarget(cfg *config.Config, paths *config.Paths) (*git.Git, string, error) {
+	symlinks, err := symlinkPolicy(cfg)
+	if err != nil {
+		return nil, "", err
 	}
 
-	if profile.User.Email != "" {
-		gitConfig["user.email"] = profile.User.Email
+	if !switchLocal && !switchWorktree {
+		mode, err := git.ParseMode(cfg.Settings.Mode)
+		if err != nil {
+			ui.PrintError(fmt.Sprintf("Invalid settings: %v", err))
+
+			return nil, "", errors.Wrap(err, "invalid write mode")
+		}
+
+		g := git.NewGit(
+			paths.GitConfigFile,
+			git.WithMode(mode),
+			git.WithIncludePath(paths.ManagedConfigFile),
+			git.WithSymlinkPolicy(symlinks),
+		)
+
+		return g, paths.GitConfigFile, checkSymlink(paths.GitConfigFile, symlinks)
 	}
 
-	if profile.User.SigningKey != "" {
-		gitConfig["user.signingkey"] = profile.User.SigningKey
+	scope := git.ScopeLocal
+	if switchWorktree {
+		scope = git.ScopeWorktree
 	}
 
-	// URL rewrites
-	for _, url := range profile.URL {
-		key := fmt.Sprintf("url \"%s\".insteadOf", url.Pattern)
-		gitConfig[key] = url.InsteadOf
+	configPath, err := git.RepoConfigPath(".", scope)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to locate repository config: %v", err))
+
+		return nil, "", errors.Wrap(err, "failed to locate repository config")
 	}
 
-	// Dynamically add all sections from the profile
-	for _, section := range config.ConfigSections {
-		if sectionMap := profile.GetSection(section); sectionMap != nil {
-			addSectionToConfig(gitConfig, section, sectionMap)
+	if scope == git.ScopeWorktree {
+		if err := git.EnableWorktreeConfig("."); err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to enable worktree config: %v", err))
+
+			return nil, "", errors.Wrap(err, "failed to enable worktree config")
 		}
 	}
 
-	return gitConfig
+	ui.PrintInfo("Writing repository config " + configPath)
+
+	// The repository config is shared with git, so only the managed block is written
+	g := git.NewGit(configPath, git.WithMode(git.ModeBlock), git.WithSymlinkPolicy(symlinks))
+
+	return g, configPath, checkSymlink(configPath, symlinks)
 }
 
-// addSectionToConfig adds a section with values to the git configuration map.
-// It delegates to addSectionToConfigRecursive for hierarchical key handling.
-func addSectionToConfig(
-	config map[string]any,
-	section string,
-	values map[string]any,
-) {
-	addSectionToConfigRecursive(config, section, values)
+// symlinkPolicy returns the symlink policy of the settings.
+func symlinkPolicy(cfg *config.Config) (git.SymlinkPolicy, error) {
+	policy, err := git.ParseSymlinkPolicy(cfg.Settings.Symlinks)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Invalid settings: %v", err))
+
+		return "", errors.Wrap(err, "invalid symlink policy")
+	}
+
+	return policy, nil
 }
 
-// addSectionToConfigRecursive recursively adds nested configuration values.
-// It handles dot-separated keys by creating nested maps as needed.
-func addSectionToConfigRecursive(
-	config map[string]any,
-	prefix string,
-	values map[string]any,
-) {
-	for k, v := range values {
-		// If the value is a map, it's a subsection - just continue with dot notation
-		// e.g., add.interactive, delta.decorations, delta.interactive
-		if m, ok := v.(map[string]any); ok {
-			key := fmt.Sprintf("%s.%s", prefix, k)
-			addSectionToConfigRecursive(config, key, m)
-		} else {
-			// Leaf value - add it directly
-			key := fmt.Sprintf("%s.%s", prefix, k)
-			config[key] = v
-		}
+// checkSymlink applies the symlink policy to a git config before it is
+// backed up and written, and tells which file a symbolic link leads to.
+func checkSymlink(path string, policy git.SymlinkPolicy) error {
+	target, err := git.CheckSymlink(path, policy)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Cannot write git config: %v", err))
+
+		return errors.Wrap(err, "cannot write git config")
+	}
+
+	if target != path {
+		ui.PrintInfo(fmt.Sprintf("%s is a symbolic link, writing %s", path, target))
 	}
+
+	return nil
 }
 
 func init() {
+	switchCmd.Flags().BoolVar(&switchLocal, "local", false, "write the profile to the current repository's config")
+	switchCmd.Flags().BoolVar(&switchWorktree, "worktree", false, "write the profile to the current worktree's config")
+	switchCmd.MarkFlagsMutuallyExclusive("local", "worktree")
 	rootCmd.AddCommand(switchCmd)
 }
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.yaml for mistakes",
+	Long: `Check config.yaml and report each problem with its line and column.
+
+Errors are invalid YAML, values of the wrong type (such as a url mapping
+instead of a list), duplicate profiles or keys, invalid settings and extends
+of unknown profiles; no command runs until they are fixed. Warnings are
+unknown keys, such as a 'usr' typo, and profiles without a user name or
+email; every command prints them, and with --strict they are fatal too.`,
+	Args: cobra.NoArgs,
+	RunE: runValidate,
+}
+
+// runValidate handles the 'validate' command.
+func runValidate(cmd *cobra.Command, args []string) error {
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	diagnostics, err := config.ValidateFile(paths.ConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to validate config: %v", err))
+
+		return errors.Wrap(err, "failed to validate config")
+	}
+
+	printDiagnostics(cmd.ErrOrStderr(), paths.ConfigFile, diagnostics)
+
+	errs, warnings := len(diagnostics.Errors()), len(diagnostics.Warnings())
+
+	switch {
+	case errs > 0:
+		return errors.WithStack(errors.Newf("%s has %d error(s) and %d warning(s)", paths.ConfigFile, errs, warnings))
+	case warnings > 0 && strictConfig:
+		return errors.WithStack(errors.Newf("%s has %d warning(s)", paths.ConfigFile, warnings))
+	case warnings > 0:
+		ui.PrintWarning(fmt.Sprintf("%s is valid, with %d warning(s)", paths.ConfigFile, warnings))
+	default:
+		ui.PrintSuccess(paths.ConfigFile + " is valid")
+	}
+
+	return nil
+}
+
+// printDiagnostics prints diagnostics prefixed with their location in file
+// to w, standard error, so that they stay out of the data commands print.
+func printDiagnostics(w io.Writer, file string, diagnostics config.Diagnostics) {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == config.SeverityError {
+			ui.FprintError(w, diagnostic.Format(file))
+		} else {
+			ui.FprintWarning(w, diagnostic.Format(file))
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aanogueira/git-context/internal/config"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/aanogueira/git-context/internal/ui"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+var whichCmd = &cobra.Command{
+	Use:   "which [path]",
+	Short: "Explain which profile a repository gets",
+	Long: `Evaluate the directories and remotes rules of every profile against the
+repository at path (the current directory by default), and report the profile
+that applies, the rule that selected it and the other matching candidates.
+A profile pinned with 'switch --local' or '--worktree' takes precedence.
+
+The result is compared with the user.email git itself resolves, to spot stale
+compiled rules or settings overridden elsewhere.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWhich,
+}
+
+// runWhich handles the 'which' command.
+func runWhich(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	paths, err := config.NewPaths(configFileFlag, gitConfigFileFlag)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to get paths: %v", err))
+
+		return errors.Wrap(err, "failed to get paths")
+	}
+
+	cfg, err := config.LoadConfig(paths.ConfigFile, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	gitDir, err := git.GitDir(dir)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to inspect repository: %v", err))
+
+		return errors.Wrap(err, "failed to inspect repository")
+	}
+
+	remotes, err := git.RemoteURLs(dir, paths.GitConfigFile)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to read remotes: %v", err))
+	}
+
+	pinned, scope, err := git.RepoProfile(dir)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to read repository config: %v", err))
+	}
+
+	home, _ := os.UserHomeDir()
+	repo := config.Repository{GitDir: gitDir, RemoteURLs: remotes}
+	matches := cfg.MatchRules(repo, home, filepath.Dir(includeIfConfigFile(cfg, paths)))
+
+	ui.PrintHeader("Profile for " + dir)
+	ui.PrintInfo("Git dir: " + gitDir)
+
+	for _, url := range remotes {
+		ui.PrintInfo("Remote: " + url)
+	}
+
+	fmt.Println()
+
+	winner, reason := "", ""
+
+	switch {
+	case pinned != "":
+		winner, reason = pinned, fmt.Sprintf("pinned with 'switch --%s'", scope)
+	case len(matches) > 0:
+		last := matches[len(matches)-1]
+		winner, reason = last.Profile, "rule "+last.Condition
+	}
+
+	if len(matches) > 0 {
+		rows := make([][]string, len(matches))
+		for i, match := range matches {
+			result := "overridden by a later rule"
+
+			switch {
+			case pinned != "":
+				result = "overridden by the pin"
+			case i == len(matches)-1:
+				result = "● (wins)"
+			}
+
+			rows[i] = []string{match.Profile, match.Condition, result}
+		}
+
+		ui.PrintTable([]string{"Profile", "Rule", "Result"}, rows)
+		fmt.Println()
+	} else {
+		ui.PrintInfo("No profile rule matches this repository")
+	}
+
+	if winner != "" {
+		ui.PrintSuccess(fmt.Sprintf("Profile: %s (%s)", winner, reason))
+	} else if cfg.Current != "" {
+		winner = cfg.Current
+		ui.PrintInfo(fmt.Sprintf("Profile: %s (active in the global git config)", winner))
+	} else {
+		ui.PrintInfo("No profile applies, the global git config is used as is")
+	}
+
+	checkGitAgrees(cfg, paths, dir, winner)
+
+	return nil
+}
+
+// checkGitAgrees compares the user.email git resolves in dir, reading the
+// managed global git config, with the one of the expected profile, or of
+// global when profileName is empty.
+func checkGitAgrees(cfg *config.Config, paths *config.Paths, dir, profileName string) {
+	expected := cfg.MergeGlobal().User.Email
+
+	if profileName != "" {
+		merged, err := cfg.Merge(profileName)
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("Cannot resolve profile '%s': %v", profileName, err))
+
+			return
+		}
+
+		expected = merged.User.Email
+	}
+
+	actual, origin, err := git.ConfigOrigin(dir, paths.GitConfigFile, "user.email")
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Failed to query git: %v", err))
+
+		return
+	}
+
+	if actual != expected {
+		ui.PrintWarning(fmt.Sprintf(
+			"git resolves user.email to '%s' (from %s), but git-context expects '%s'",
+			actual, origin, expected,
+		))
+		ui.PrintInfo("Run 'git-context compile' if the rules changed since the last compile")
+
+		return
+	}
+
+	if actual != "" {
+		ui.PrintInfo(fmt.Sprintf("git agrees: user.email = %s (from %s)", actual, origin))
+	}
+}
+
+// includeIfConfigFile returns the file compile writes the includeIf entries
+// to, which relative "./" gitdir patterns are resolved against.
+func includeIfConfigFile(cfg *config.Config, paths *config.Paths) string {
+	if mode, err := git.ParseMode(cfg.Settings.Mode); err == nil && mode == git.ModeInclude {
+		return paths.ManagedConfigFile
+	}
+
+	return paths.GitConfigFile
+}
+
+func init() {
+	rootCmd.AddCommand(whichCmd)
+}
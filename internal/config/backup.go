@@ -0,0 +1,261 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// DefaultBackupRetention is the number of backups kept when the setting is unset.
+const DefaultBackupRetention = 10
+
+// File name suffixes of a backup: the copied config and its metadata.
+const (
+	backupDataSuffix = ".gitconfig"
+	backupMetaSuffix = ".yaml"
+)
+
+// backupIDLayout formats backup IDs, which sort in creation order.
+const backupIDLayout = "20060102-150405"
+
+// Backup is a copy of a git config file taken before git-context wrote it.
+type Backup struct {
+	ID      string    `yaml:"-"`
+	Source  string    `yaml:"source"`
+	Profile string    `yaml:"profile,omitempty"`
+	Created time.Time `yaml:"created"`
+
+	path string
+}
+
+// Path returns the location of the backed up content.
+func (b *Backup) Path() string {
+	return b.path
+}
+
+// BackupStore keeps timestamped backups in a directory, dropping the oldest
+// beyond its retention.
+type BackupStore struct {
+	dir       string
+	retention int
+}
+
+// NewBackupStore returns the store of the backups in dir. A retention below
+// one keeps DefaultBackupRetention backups.
+func NewBackupStore(dir string, retention int) *BackupStore {
+	if retention < 1 {
+		retention = DefaultBackupRetention
+	}
+
+	return &BackupStore{dir: dir, retention: retention}
+}
+
+// Save backs up source, recording profile as the profile active at the time.
+// It returns nil when source does not exist.
+func (s *BackupStore) Save(source, profile string) (*Backup, error) {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create backups directory")
+	}
+
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve backup source")
+	}
+
+	backup := &Backup{Source: source, Profile: profile, Created: time.Now().Truncate(time.Second)}
+
+	backup.ID, err = s.nextID(backup.Created)
+	if err != nil {
+		return nil, err
+	}
+
+	backup.path = filepath.Join(s.dir, backup.ID+backupDataSuffix)
+
+	if err := git.NewGit(source).BackupConfig(backup.path); err != nil {
+		return nil, err
+	}
+
+	meta, err := yaml.Marshal(backup)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal backup metadata")
+	}
+
+	if err := fsutil.WriteFile(filepath.Join(s.dir, backup.ID+backupMetaSuffix), meta, 0o644); err != nil {
+		return nil, errors.Wrap(err, "failed to write backup metadata")
+	}
+
+	return backup, s.prune()
+}
+
+// List returns the backups, newest first.
+func (s *BackupStore) List() ([]*Backup, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to read backups directory")
+	}
+
+	var backups []*Backup
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), backupMetaSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		backup, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, backup)
+	}
+
+	slices.SortFunc(backups, func(a, b *Backup) int {
+		return cmp.Or(b.Created.Compare(a.Created), cmp.Compare(len(b.ID), len(a.ID)), cmp.Compare(b.ID, a.ID))
+	})
+
+	return backups, nil
+}
+
+// Find returns the backup with the given ID, or the latest one when id is empty.
+func (s *BackupStore) Find(id string) (*Backup, error) {
+	if id != "" {
+		if !s.exists(id) {
+			return nil, errors.WithStack(errors.Newf("backup '%s' does not exist", id))
+		}
+
+		return s.load(id)
+	}
+
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(backups) == 0 {
+		return nil, errors.New("no backups found")
+	}
+
+	return backups[0], nil
+}
+
+// Restore writes the content of a backup back to its source. The current
+// content is backed up first, recording profile as the active one, so that
+// the restore can be undone; that backup is returned, nil when the source
+// did not exist.
+func (s *BackupStore) Restore(backup *Backup, profile string) (*Backup, error) {
+	// Read before backing up the current content, whose pruning may remove
+	// the backup being restored
+	data, err := os.ReadFile(backup.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read backup")
+	}
+
+	undo, err := s.Save(backup.Source, profile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to back up current content")
+	}
+
+	if err := fsutil.WriteFile(backup.Source, data, 0o644); err != nil {
+		return nil, errors.Wrap(err, "failed to restore backup")
+	}
+
+	return undo, nil
+}
+
+// nextID returns the ID of a backup created at created. Backups taken within
+// the same second get a counter, which keeps growing even once the earlier
+// ones are pruned so that IDs stay in creation order.
+func (s *BackupStore) nextID(created time.Time) (string, error) {
+	base := created.Format(backupIDLayout)
+
+	backups, err := s.List()
+	if err != nil {
+		return "", err
+	}
+
+	next := 1
+
+	for _, other := range backups {
+		if other.ID == base {
+			next = max(next, 2)
+		} else if counter, ok := strings.CutPrefix(other.ID, base+"-"); ok {
+			if n, err := strconv.Atoi(counter); err == nil {
+				next = max(next, n+1)
+			}
+		}
+	}
+
+	if next == 1 {
+		return base, nil
+	}
+
+	return fmt.Sprintf("%s-%d", base, next), nil
+}
+
+// exists reports whether a backup with the given ID exists.
+func (s *BackupStore) exists(id string) bool {
+	// IDs are file names, a path would escape the backups directory
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(s.dir, id+backupMetaSuffix))
+
+	return err == nil
+}
+
+// load reads the metadata of a backup.
+func (s *BackupStore) load(id string) (*Backup, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, id+backupMetaSuffix))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read backup metadata")
+	}
+
+	backup := &Backup{}
+	if err := yaml.Unmarshal(data, backup); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse metadata of backup '%s'", id)
+	}
+
+	backup.ID = id
+	backup.path = filepath.Join(s.dir, id+backupDataSuffix)
+
+	return backup, nil
+}
+
+// prune removes the oldest backups beyond the retention.
+func (s *BackupStore) prune() error {
+	backups, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups[min(s.retention, len(backups)):] {
+		for _, path := range []string{backup.path, filepath.Join(s.dir, backup.ID+backupMetaSuffix)} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "failed to remove old backup")
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, ".gitconfig")
+	store := NewBackupStore(filepath.Join(dir, "backups"), 3)
+
+	if backup, err := store.Save(source, "work"); err != nil || backup != nil {
+		t.Fatalf("Expected no backup of a missing file, got %v (%v)", backup, err)
+	}
+
+	if _, err := store.Find(""); err == nil {
+		t.Error("Expected an error without backups")
+	}
+
+	// Backups taken in the same second get distinct IDs
+	var ids []string
+
+	for i := range 5 {
+		if err := os.WriteFile(source, []byte{byte('a' + i)}, 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		backup, err := store.Save(source, "work")
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+
+		ids = append(ids, backup.ID)
+	}
+
+	backups, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	if len(backups) != 3 {
+		t.Fatalf("Expected the retention to keep 3 backups, got %d", len(backups))
+	}
+
+	for i, backup := range backups {
+		if backup.ID != ids[len(ids)-1-i] || backup.Profile != "work" || backup.Source != source {
+			t.Errorf("Unexpected backup %d: %+v", i, backup)
+		}
+	}
+
+	latest, err := store.Find("")
+	if err != nil || latest.ID != ids[4] {
+		t.Fatalf("Expected the latest backup, got %v (%v)", latest, err)
+	}
+
+	if _, err := store.Find(ids[0]); err == nil {
+		t.Error("Expected the oldest backup to be pruned")
+	}
+
+	if _, err := store.Find("../backups"); err == nil {
+		t.Error("Expected a path to be rejected as an ID")
+	}
+
+	oldest, err := store.Find(ids[2])
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	undo, err := store.Restore(oldest, "work")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(source); string(data) != "c" {
+		t.Errorf("Expected the backup content to be restored, got %q", data)
+	}
+
+	if data, _ := os.ReadFile(undo.Path()); string(data) != "e" {
+		t.Errorf("Expected the replaced content to be backed up, got %q", data)
+	}
+}
+
+func TestBackupStoreRestoreWithRetentionOne(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, ".gitconfig")
+	store := NewBackupStore(filepath.Join(dir, "backups"), 1)
+
+	if err := os.WriteFile(source, []byte("old"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	backup, err := store.Save(source, "work")
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := os.WriteFile(source, []byte("new"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Backing up the current content prunes the backup being restored
+	undo, err := store.Restore(backup, "work")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(source); string(data) != "old" {
+		t.Errorf("Expected the backup content to be restored, got %q", data)
+	}
+
+	backups, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	if len(backups) != 1 || backups[0].ID != undo.ID {
+		t.Fatalf("Expected only the backup of the replaced content, got %v", backups)
+	}
+
+	if data, _ := os.ReadFile(undo.Path()); string(data) != "new" {
+		t.Errorf("Expected the replaced content to be backed up, got %q", data)
+	}
+}
+
+func TestNewBackupStoreDefaultRetention(t *testing.T) {
+	t.Parallel()
+
+	if store := NewBackupStore(t.TempDir(), 0); store.retention != DefaultBackupRetention {
+		t.Errorf("Expected the default retention, got %d", store.retention)
+	}
+}
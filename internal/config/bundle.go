@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// Bundle is a single profile together with the global entries it depends on,
+// in the layout of config.yaml, so it can be moved to another machine.
+type Bundle struct {
+	Global   map[string]any      `yaml:"global,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// NewBundle exports a profile as a bundle. The profiles it extends are
+// flattened into it, and global keeps only the entries the merged profile
+// takes from global; global keys the profile unsets are unset explicitly.
+func (c *Config) NewBundle(profileName string) (*Bundle, error) {
+	merged, origins, err := c.MergeWithOrigins(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	source := c.Profiles[profileName]
+	exported := &Config{
+		Global: make(map[string]any),
+		Profiles: map[string]*Profile{
+			profileName: {
+				Directories: slices.Clone(source.Directories),
+				Remotes:     slices.Clone(source.Remotes),
+				Sections:    make(map[string]any),
+			},
+		},
+	}
+
+	gitConfig := merged.GitConfig()
+	values := git.ConfigValues(gitConfig)
+
+	for _, key := range slices.Sorted(maps.Keys(gitConfig)) {
+		target := profileName
+		if origins.Of(key) == GlobalLayer {
+			target = GlobalLayer
+		}
+
+		if err := exported.SetValue(target, key, values[git.CanonicalKey(key)]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(c.MergeGlobal().GitConfig())) {
+		if _, kept := values[git.CanonicalKey(key)]; !kept {
+			exported.setUnset(profileName, key)
+		}
+	}
+
+	return &Bundle{Global: exported.Global, Profiles: exported.Profiles}, nil
+}
+
+// LoadBundle reads a bundle file holding exactly one profile.
+func LoadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bundle")
+	}
+
+	// A bundle has the layout of config.yaml and is checked the same way
+	if errs := Validate(data).Errors(); len(errs) > 0 {
+		return nil, errors.WithStack(&ValidationError{File: path, Diagnostics: errs})
+	}
+
+	bundle := &Bundle{}
+	if err := yaml.Unmarshal(data, bundle); err != nil {
+		return nil, errors.Wrap(err, "failed to parse bundle")
+	}
+
+	if len(bundle.Profiles) != 1 {
+		return nil, errors.WithStack(errors.Newf("bundle must hold exactly one profile, found %d", len(bundle.Profiles)))
+	}
+
+	return bundle, nil
+}
+
+// ProfileName returns the name of the bundled profile.
+func (b *Bundle) ProfileName() string {
+	for name := range b.Profiles {
+		return name
+	}
+
+	return ""
+}
+
+// ImportBundle adds the bundled profile as name, replacing an existing
+// profile of that name. Global is never changed, as every other profile
+// inherits it: bundled global entries missing or set differently here are
+// stored in the profile, so it resolves as it did where it was exported; url
+// rewrites are handled as a whole. The returned notes describe those entries.
+func (c *Config) ImportBundle(bundle *Bundle, name string) ([]string, error) {
+	profile := bundle.Profiles[bundle.ProfileName()]
+	if profile == nil {
+		profile = &Profile{}
+	}
+
+	// The bundle is self-contained, an extends list would point at profiles of the exporting machine
+	profile.Extends = nil
+	c.Profiles[name] = profile
+
+	source := &Config{Global: bundle.Global}
+	bundled := source.MergeGlobal().GitConfig()
+	bundledValues := git.ConfigValues(bundled)
+	current := git.ConfigValues(c.MergeGlobal().GitConfig())
+	own := git.ConfigValues(profile.GitConfig())
+
+	var notes []string
+
+	// URL rewrites are replaced as a whole, so they are compared as a whole
+	urls, currentURLs := globalURLs(bundle.Global), globalURLs(c.Global)
+	if len(profile.URL) == 0 && len(urls) > 0 && !slices.Equal(urls, currentURLs) {
+		profile.URL = urls
+
+		state := "not in global"
+		if len(currentURLs) > 0 {
+			state = "global has others"
+		}
+
+		notes = append(notes, fmt.Sprintf("url rewrites: %s, kept in profile '%s'", state, name))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(bundled)) {
+		canonical := git.CanonicalKey(key)
+		want := bundledValues[canonical]
+
+		if section, _, _, _ := git.SplitKey(canonical); section == "url" {
+			continue
+		}
+
+		if slices.Equal(current[canonical], want) || len(own[canonical]) > 0 {
+			continue
+		}
+
+		state := "not in global"
+		if len(current[canonical]) > 0 {
+			state = "global has " + strings.Join(current[canonical], ", ")
+		}
+
+		notes = append(notes, fmt.Sprintf("%s = %s: %s, kept in profile '%s'", key, strings.Join(want, ", "), state, name))
+
+		if err := c.SetValue(name, key, want); err != nil {
+			return nil, err
+		}
+	}
+
+	return notes, nil
+}
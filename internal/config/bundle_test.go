@@ -0,0 +1,189 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+func TestNewBundle(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "vim", "pager": "less"},
+			"pull": map[string]any{"rebase": true},
+			"url":  []URLConfig{{Pattern: "git@github.com:", InsteadOf: "https://github.com/"}},
+		},
+		Profiles: map[string]*Profile{
+			"base": {
+				Sections: map[string]any{"commit": map[string]any{"gpgSign": true}},
+			},
+			"work": {
+				Extends:     []string{"base"},
+				Directories: []string{"~/work"},
+				User:        UserConfig{Name: "Jane Doe", Email: "jane@work.example"},
+				Sections: map[string]any{
+					"core": map[string]any{"pager": nil},
+				},
+			},
+		},
+	}
+
+	bundle, err := cfg.NewBundle("work")
+	if err != nil {
+		t.Fatalf("NewBundle failed: %v", err)
+	}
+
+	if got := bundle.ProfileName(); got != "work" || len(bundle.Profiles) != 1 {
+		t.Fatalf("Expected only the work profile, got %v", slices.Collect(maps.Keys(bundle.Profiles)))
+	}
+
+	work := bundle.Profiles["work"]
+	if len(work.Extends) != 0 || work.GetSection("commit")["gpgSign"] != true {
+		t.Errorf("Expected the extended profile to be flattened, got %+v", work)
+	}
+
+	if pager, exists := work.GetSection("core")["pager"]; !exists || pager != nil {
+		t.Errorf("Expected core.pager to stay unset, got %v", work.GetSection("core"))
+	}
+
+	if !slices.Equal(work.Directories, []string{"~/work"}) {
+		t.Errorf("Expected the directory rules to be kept, got %v", work.Directories)
+	}
+
+	core, _ := bundle.Global["core"].(map[string]any)
+	if len(core) != 1 || core["editor"] != "vim" || len(globalURLs(bundle.Global)) != 1 {
+		t.Errorf("Expected only the global entries in use, got %v", bundle.Global)
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	t.Parallel()
+
+	source := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "vim", "pager": "less"},
+			"pull": map[string]any{"rebase": true},
+			"url":  []URLConfig{{Pattern: "git@github.com:", InsteadOf: "https://github.com/"}},
+		},
+		Profiles: map[string]*Profile{
+			"base": {
+				Sections: map[string]any{"commit": map[string]any{"gpgSign": true}},
+			},
+			"work": {
+				Extends:     []string{"base"},
+				Directories: []string{"~/work"},
+				User:        UserConfig{Name: "Jane Doe", Email: "jane@work.example"},
+				Sections: map[string]any{
+					"core": map[string]any{"pager": nil},
+				},
+			},
+		},
+	}
+
+	bundle, err := source.NewBundle("work")
+	if err != nil {
+		t.Fatalf("NewBundle failed: %v", err)
+	}
+
+	// Round trip through YAML, as between two machines
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "work.yaml")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	loaded, err := LoadBundle(path)
+	if err != nil {
+		t.Fatalf("LoadBundle failed: %v", err)
+	}
+
+	target := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "nano", "pager": "more"},
+			"url":  []URLConfig{{Pattern: "ssh://git@gitlab.com/", InsteadOf: "https://gitlab.com/"}},
+		},
+		Profiles: map[string]*Profile{"personal": {}},
+	}
+
+	notes, err := target.ImportBundle(loaded, "client")
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+
+	if len(notes) != 3 {
+		t.Errorf("Expected 3 notes, got %v", notes)
+	}
+
+	want, err := source.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	got, err := target.Merge("client")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	wantValues, gotValues := git.ConfigValues(want.GitConfig()), git.ConfigValues(got.GitConfig())
+	if !maps.EqualFunc(wantValues, gotValues, slices.Equal) {
+		t.Errorf("Expected the imported profile to resolve as exported\nwant %v\ngot  %v", wantValues, gotValues)
+	}
+
+	// Global is left alone, so other profiles resolve as before
+	personal, err := target.Merge("personal")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if _, added := personal.GetSection("pull")["rebase"]; added || personal.GetSection("core")["editor"] != "nano" {
+		t.Errorf("Unexpected personal profile: %v", personal.Sections)
+	}
+
+	if len(target.Global) != 2 || len(globalURLs(target.Global)) != 1 {
+		t.Errorf("Expected global to be unchanged, got %v", target.Global)
+	}
+}
+
+func TestLoadBundleValidates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bundle.yaml")
+	source := "profiles:\n  work:\n    url:\n      pattern: git@github.com:\n"
+
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	_, err := LoadBundle(path)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error for a url mapping, got %v", err)
+	}
+}
+
+func TestLoadBundleRequiresOneProfile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bundle.yaml")
+	if err := os.WriteFile(path, []byte("profiles:\n  a: {}\n  b: {}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	if _, err := LoadBundle(path); err == nil {
+		t.Error("Expected a bundle with two profiles to be rejected")
+	}
+}
@@ -4,47 +4,34 @@ import (
 	"fmt"
 	"maps"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/aanogueira/git-context/internal/git"
 	"github.com/cockroachdb/errors"
 )
 
 // Profile represents a git configuration profile.
+// The user and url sections are typed; every other key is a git section,
+// optionally with a quoted subsection such as `credential "https://github.com"`.
 type Profile struct {
-	Add         map[string]any `yaml:"add,omitempty"`
-	Alias       map[string]any `yaml:"alias,omitempty"`
-	Branch      map[string]any `yaml:"branch,omitempty"`
-	Column      map[string]any `yaml:"column,omitempty"`
-	Commit      map[string]any `yaml:"commit,omitempty"`
-	Core        map[string]any `yaml:"core,omitempty"`
-	Custom      map[string]any `yaml:"custom,omitempty"`
-	Delta       map[string]any `yaml:"delta,omitempty"`
-	Diff        map[string]any `yaml:"diff,omitempty"`
-	Feature     map[string]any `yaml:"feature,omitempty"`
-	Fetch       map[string]any `yaml:"fetch,omitempty"`
-	GPG         map[string]any `yaml:"gpg,omitempty"`
-	HTTP        map[string]any `yaml:"http,omitempty"`
-	Init        map[string]any `yaml:"init,omitempty"`
-	Interactive map[string]any `yaml:"interactive,omitempty"`
-	Maintenance map[string]any `yaml:"maintenance,omitempty"`
-	Merge       map[string]any `yaml:"merge,omitempty"`
-	Pull        map[string]any `yaml:"pull,omitempty"`
-	Push        map[string]any `yaml:"push,omitempty"`
-	Rebase      map[string]any `yaml:"rebase,omitempty"`
-	Rerere      map[string]any `yaml:"rerere,omitempty"`
-	Tag         map[string]any `yaml:"tag,omitempty"`
+	Extends     []string       `yaml:"extends,omitempty"`     // Profiles inherited from, in order
+	Directories []string       `yaml:"directories,omitempty"` // Repositories below these use the profile
+	Remotes     []string       `yaml:"remotes,omitempty"`     // Repositories with a matching remote URL use the profile
 	URL         []URLConfig    `yaml:"url,omitempty"`
 	User        UserConfig     `yaml:"user,omitempty"`
+	Sections    map[string]any `yaml:",inline"`
 }
 
 // UserConfig represents git user section.
 type UserConfig struct {
-	Name       string `yaml:"name"`
-	Email      string `yaml:"email"`
-	SigningKey string `yaml:"signingkey,omitempty"`
+	Name       string         `yaml:"name"`
+	Email      string         `yaml:"email"`
+	SigningKey string         `yaml:"signingkey,omitempty"`
+	Extra      map[string]any `yaml:",inline"` // Other user.* keys, e.g. useConfigOnly
 }
 
 // URLConfig represents git url rewrite rules.
@@ -53,24 +40,45 @@ type URLConfig struct {
 	InsteadOf string `yaml:"insteadOf"`
 }
 
+// Settings controls how git-context manages files on disk.
+type Settings struct {
+	// Mode selects how the global git config is written:
+	// "overwrite" (default), "include" or "block".
+	Mode string `yaml:"mode,omitempty"`
+	// BackupRetention is the number of git config backups kept,
+	// DefaultBackupRetention when unset.
+	BackupRetention int `yaml:"backupRetention,omitempty"`
+	// Symlinks selects how a git config that is a symbolic link is written:
+	// "follow" (default) writes the file it points to, "refuse" fails.
+	Symlinks string `yaml:"symlinks,omitempty"`
+}
+
 // Config represents the entire configuration.
 type Config struct {
+	Version  int                 `yaml:"version"` // Format version, see ConfigVersion
+	Settings Settings            `yaml:"settings,omitempty"`
 	Global   map[string]any      `yaml:"global"`
 	Profiles map[string]*Profile `yaml:"profiles"`
 	Current  string              `yaml:"-"` // Not saved, determined at runtime
+	Modified bool                `yaml:"-"` // The live git config no longer matches Current
+
+	doc       *document  // The loaded file, so saving keeps its layout
+	migration *Migration // Upgrade of the loaded file, backed up on the first save
 }
 
 // NewConfig creates a new empty config.
 func NewConfig() *Config {
 	return &Config{
+		Version:  ConfigVersion,
 		Global:   make(map[string]any),
 		Profiles: make(map[string]*Profile),
 		Current:  "",
 	}
 }
 
-// LoadConfig loads the configuration from file.
-func LoadConfig(configFile string) (*Config, error) {
+// LoadConfig loads the configuration from file. The active profile is
+// determined from the global git config at gitConfigFile, unless it is empty.
+func LoadConfig(configFile, gitConfigFile string) (*Config, error) {
 	// If file doesn't exist, return empty config
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		return NewConfig(), nil
@@ -81,31 +89,83 @@ func LoadConfig(configFile string) (*Config, error) {
 		return nil, errors.Wrap(err, "failed to read config file")
 	}
 
+	if errs := Validate(data).Errors(); len(errs) > 0 {
+		return nil, errors.WithStack(&ValidationError{File: configFile, Diagnostics: errs})
+	}
+
+	// Older formats are upgraded in memory, the file is rewritten on save
+	migration, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseDocument(migration.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse config file")
+	}
+
 	config := NewConfig()
-	if err := yaml.Unmarshal(data, config); err != nil {
+	if err := doc.decode(config); err != nil {
 		return nil, errors.Wrap(err, "failed to parse config file")
 	}
 
+	config.doc = doc
+
+	if migration.Needed() {
+		config.migration = migration
+	}
+
 	// Determine current profile by checking git config
-	config.determineCurrent()
+	if gitConfigFile != "" {
+		config.determineCurrent(gitConfigFile)
+	}
 
 	return config, nil
 }
 
-// SaveConfig saves the configuration to file.
+// SaveConfig saves the configuration to file. A configuration loaded from a
+// file keeps its comments, blank lines and key order: only the entries that
+// changed are rewritten. The first save of a file in an older format keeps
+// the original next to it.
 func (c *Config) SaveConfig(configFile string) error {
-	data, err := yaml.Marshal(c)
+	data, err := c.marshal()
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal config")
+		return err
 	}
 
-	if err := os.WriteFile(configFile, data, 0o644); err != nil {
+	if c.migration != nil {
+		c.migration.Data = data
+		if err := c.migration.Save(configFile); err != nil {
+			return err
+		}
+
+		c.migration = nil
+	} else if err := fsutil.WriteFile(configFile, data, 0o644); err != nil {
 		return errors.Wrap(err, "failed to write config file")
 	}
 
+	// Later saves edit the file as now written
+	if doc, err := parseDocument(data); err == nil {
+		c.doc = doc
+	}
+
 	return nil
 }
 
+// marshal returns the content of config.yaml holding the configuration.
+func (c *Config) marshal() ([]byte, error) {
+	if c.doc == nil {
+		data, err := yaml.Marshal(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal config")
+		}
+
+		return data, nil
+	}
+
+	return c.doc.update(c)
+}
+
 // AddProfile adds a new profile.
 func (c *Config) AddProfile(name string, profile *Profile) error {
 	if _, exists := c.Profiles[name]; exists {
@@ -118,11 +178,18 @@ func (c *Config) AddProfile(name string, profile *Profile) error {
 }
 
 // RemoveProfile removes a profile.
+// A profile that other profiles extend cannot be removed.
 func (c *Config) RemoveProfile(name string) error {
 	if _, exists := c.Profiles[name]; !exists {
 		return errors.WithStack(errors.Newf("profile '%s' does not exist", name))
 	}
 
+	for _, other := range slices.Sorted(maps.Keys(c.Profiles)) {
+		if c.Profiles[other] != nil && slices.Contains(c.Profiles[other].Extends, name) {
+			return errors.WithStack(errors.Newf("profile '%s' is extended by '%s'", name, other))
+		}
+	}
+
 	delete(c.Profiles, name)
 
 	return nil
@@ -148,107 +215,262 @@ func (c *Config) ListProfiles() []string {
 	return profiles
 }
 
+// GlobalSections returns the sorted names of the sections defined in global.
+// They are written first so the generated git config has a stable layout.
+func (c *Config) GlobalSections() []string {
+	sections := make([]string, 0, len(c.Global))
+	for name := range c.Global {
+		section, _, _ := SplitSectionName(name)
+		sections = append(sections, strings.ToLower(section))
+	}
+
+	slices.Sort(sections)
+
+	return slices.Compact(sections)
+}
+
 // Merge combines global config with profile config.
+// The profiles listed in extends are applied in between, see Chain.
 func (c *Config) Merge(profileName string) (*Profile, error) {
-	profile, err := c.GetProfile(profileName)
+	merged, _, err := c.MergeWithOrigins(profileName)
+
+	return merged, err
+}
+
+// MergeWithOrigins is like Merge and also reports which layer, global or
+// one of the profiles in the chain, supplied each key of the result.
+func (c *Config) MergeWithOrigins(profileName string) (*Profile, Origins, error) {
+	chain, err := c.Chain(profileName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Helper to get section from global config
-	getGlobalSection := func(section string) map[string]any {
-		if val, exists := c.Global[section]; exists {
-			if m, ok := val.(map[string]any); ok {
-				return m
-			}
-		}
+	layers := make([]layer, 0, len(chain)+1)
+	layers = append(layers, c.globalLayer())
 
-		return make(map[string]any)
-	}
-
-	// Merge URLs: profile URLs override global URLs
-	mergedURLs := profile.URL
-	if len(mergedURLs) == 0 && c.Global["url"] != nil {
-		// If profile has no URLs, use global URLs
-		if urlList, ok := c.Global["url"].([]URLConfig); ok {
-			mergedURLs = urlList
-		} else if urlList, ok := c.Global["url"].([]any); ok {
-			// Handle case where URL is unmarshalled as []interface{}
-			for _, item := range urlList {
-				if urlMap, ok := item.(map[string]any); ok {
-					mergedURLs = append(mergedURLs, URLConfig{
-						Pattern:   fmt.Sprintf("%v", urlMap["pattern"]),
-						InsteadOf: fmt.Sprintf("%v", urlMap["insteadOf"]),
-					})
-				}
-			}
-		}
+	for _, name := range chain {
+		layers = append(layers, profileLayer(name, c.Profiles[name]))
 	}
 
-	// Create a new merged profile
-	merged := &Profile{
-		User: profile.User,
-		URL:  mergedURLs,
-	}
+	merged, origins := mergeLayers(layers)
 
-	// Dynamically merge all sections
-	for _, section := range ConfigSections {
-		merged.SetSection(section, mergeMap(
-			getGlobalSection(section),
-			profile.GetSection(section),
-		))
+	// The custom section is the profile's own, it is not merged
+	if custom := c.Profiles[profileName].GetSection(customSection); custom != nil {
+		merged.SetSection(customSection, custom)
 	}
 
-	// Custom section is not merged with global
-	merged.Custom = profile.Custom
+	return merged, origins, nil
+}
 
-	return merged, nil
+// MergeGlobal returns the configuration resolved from global alone.
+func (c *Config) MergeGlobal() *Profile {
+	merged, _ := mergeLayers([]layer{c.globalLayer()})
+
+	return merged
+}
+
+// globalLayer returns the global section as a merge layer.
+func (c *Config) globalLayer() layer {
+	sections := canonicalSections(c.Global)
+	delete(sections, customSection)
+
+	return layer{
+		name:     GlobalLayer,
+		urls:     globalURLs(c.Global),
+		sections: sections,
+	}
 }
 
-// determineCurrent determines which profile is currently active by checking git config.
-func (c *Config) determineCurrent() {
-	// Get current git user.name from git config
-	cmd := exec.Command("git", "config", "--global", "user.name")
+// globalURLs returns the url rewrites defined in global.
+func globalURLs(global map[string]any) []URLConfig {
+	if urlList, ok := global["url"].([]URLConfig); ok {
+		return urlList
+	}
 
-	output, err := cmd.Output()
+	var urls []URLConfig
+
+	// Handle case where URL is unmarshalled as []interface{}
+	if urlList, ok := global["url"].([]any); ok {
+		for _, item := range urlList {
+			if urlMap, ok := item.(map[string]any); ok {
+				urls = append(urls, URLConfig{
+					Pattern:   fmt.Sprintf("%v", urlMap["pattern"]),
+					InsteadOf: fmt.Sprintf("%v", urlMap["insteadOf"]),
+				})
+			}
+		}
+	}
+
+	return urls
+}
+
+// KeyCollisions reports section and key names of a profile, and of global,
+// that differ only in case. Git treats them as the same key, so only one of
+// the values can take effect.
+func (c *Config) KeyCollisions(profileName string) ([]string, error) {
+	profile, err := c.GetProfile(profileName)
 	if err != nil {
-		return // Can't determine current profile
+		return nil, err
 	}
 
-	currentName := strings.TrimSpace(string(output))
+	warnings := findCollisions("global", c.Global)
+	warnings = append(warnings, findCollisions(fmt.Sprintf("profile '%s'", profileName), profile.Sections)...)
 
-	// Get current git user.email from git config
-	cmd = exec.Command("git", "config", "--global", "user.email")
+	return warnings, nil
+}
 
-	output, err = cmd.Output()
+// determineCurrent determines which profile is currently active by reading
+// the git config file at gitConfigFile.
+func (c *Config) determineCurrent(gitConfigFile string) {
+	values, err := git.NewGit(gitConfigFile).ReadValues()
 	if err != nil {
 		return // Can't determine current profile
 	}
 
-	currentEmail := strings.TrimSpace(string(output))
+	// The marker written by switch names the profile, even when several
+	// profiles share the same identity
+	if marker := values.Get(git.ProfileKey); marker != "" {
+		if _, exists := c.Profiles[marker]; exists {
+			c.Current = marker
+			c.Modified = !c.matchesLive(marker, values)
+
+			return
+		}
+	}
+
+	currentName := values.Get("user.name")
+	currentEmail := values.Get("user.email")
+
+	if currentName == "" && currentEmail == "" {
+		return
+	}
+
+	// Match against the identity each profile resolves to, inherited values
+	// included. A profile whose other values match too is preferred.
+	for _, profileName := range slices.Sorted(maps.Keys(c.Profiles)) {
+		profile, err := c.Merge(profileName)
+		if err != nil {
+			continue
+		}
 
-	// Match against profiles
-	for profileName, profile := range c.Profiles {
-		if profile.User.Name == currentName && profile.User.Email == currentEmail {
+		if profile.User.Name != currentName || profile.User.Email != currentEmail {
+			continue
+		}
+
+		modified := !c.matchesLive(profileName, values)
+		if c.Current == "" || (c.Modified && !modified) {
 			c.Current = profileName
+			c.Modified = modified
+		}
+	}
+}
 
-			return
+// matchesLive reports whether the live git values still hold every value the
+// profile writes. Values may repeat earlier in the file, for instance from an
+// include, but the last ones must be the profile's.
+func (c *Config) matchesLive(profileName string, live git.Values) bool {
+	merged, err := c.Merge(profileName)
+	if err != nil {
+		return false
+	}
+
+	for key, want := range git.ConfigValues(merged.GitConfig()) {
+		got := live[key]
+		if len(got) < len(want) || !slices.Equal(got[len(got)-len(want):], want) {
+			return false
 		}
 	}
+
+	return true
 }
 
+// appendSuffix marks a list key in a profile whose values are appended to the
+// inherited list instead of replacing it, e.g. "directory+".
+const appendSuffix = "+"
+
 // mergeMap merges two maps, with values from profileConfig overriding globalConfig.
+// Nested maps are merged recursively and a nil (YAML null) value removes the
+// inherited key. Lists replace inherited lists unless the key ends with
+// appendSuffix, in which case they are appended to them.
+// Key names are matched case-insensitively like git does, while nested maps
+// are subsections whose names are case-sensitive. The inputs are never modified.
 func mergeMap(
 	globalConfig map[string]any,
 	profileConfig map[string]any,
 ) map[string]any {
 	result := make(map[string]any)
 
-	// First add global config values
-	maps.Copy(result, globalConfig)
-
-	// Then override with profile-specific values
-	maps.Copy(result, profileConfig)
+	// First add global config values, then override with profile-specific values
+	for _, values := range []map[string]any{globalConfig, profileConfig} {
+		// Sorted so a plain key is applied before its appending variant
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			name, appending := strings.CutSuffix(key, appendSuffix)
+			value := values[key]
+
+			nested, isMap := value.(map[string]any)
+			existing := findKey(result, name, !isMap || appending)
+
+			switch {
+			case appending:
+				inherited := result[existing]
+				delete(result, existing)
+				result[name] = appendValues(inherited, value)
+			case value == nil:
+				delete(result, existing)
+			case isMap:
+				base, _ := result[name].(map[string]any)
+				result[name] = mergeMap(base, nested)
+			default:
+				delete(result, existing)
+				result[name] = value
+			}
+		}
+	}
 
 	return result
 }
+
+// findKey returns the key of values matching name. Variable names (leaf) are
+// compared case-insensitively, subsection names exactly. It returns an empty
+// string when there is no match.
+func findKey(values map[string]any, name string, leaf bool) string {
+	if _, exists := values[name]; exists {
+		return name
+	}
+
+	if !leaf {
+		return ""
+	}
+
+	for key, value := range values {
+		if _, isMap := value.(map[string]any); !isMap && strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// appendValues concatenates two config values into a single list.
+func appendValues(base, extra any) []any {
+	return append(toList(base), toList(extra)...)
+}
+
+// toList converts a config value into a list of values.
+func toList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return slices.Clone(v)
+	case []string:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+
+		return list
+	default:
+		return []any{v}
+	}
+}
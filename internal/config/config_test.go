@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -185,7 +187,7 @@ func TestSaveAndLoadConfig(t *testing.T) {
 	}
 
 	// Load config
-	loadedCfg, err := LoadConfig(configFile)
+	loadedCfg, err := LoadConfig(configFile, "")
 	if err != nil {
 		t.Fatalf("LoadConfig failed: %v", err)
 	}
@@ -216,7 +218,7 @@ func TestSaveAndLoadConfig(t *testing.T) {
 func TestLoadConfigNonExistent(t *testing.T) {
 	t.Parallel()
 
-	cfg, err := LoadConfig("/nonexistent/path/config.yaml")
+	cfg, err := LoadConfig("/nonexistent/path/config.yaml", "")
 	if err != nil {
 		t.Errorf("LoadConfig should not fail for non-existent file: %v", err)
 	}
@@ -252,11 +254,13 @@ func TestMerge(t *testing.T) {
 			Name:  "Test User",
 			Email: "test@example.com",
 		},
-		Core: map[string]any{
-			"autocrlf": "false", // Override global
-		},
-		HTTP: map[string]any{
-			"postBuffer": "524288000",
+		Sections: map[string]any{
+			"core": map[string]any{
+				"autocrlf": "false", // Override global
+			},
+			"http": map[string]any{
+				"postBuffer": "524288000",
+			},
 		},
 	}
 	if err := cfg.AddProfile("test", profile); err != nil {
@@ -275,21 +279,21 @@ func TestMerge(t *testing.T) {
 	}
 
 	// Verify core config
-	if merged.Core["editor"] != "vim" {
+	if merged.GetSection("core")["editor"] != "vim" {
 		t.Error("Core.editor should be inherited from global")
 	}
 
-	if merged.Core["autocrlf"] != "false" {
+	if merged.GetSection("core")["autocrlf"] != "false" {
 		t.Error("Core.autocrlf should be overridden by profile")
 	}
 
 	// Verify push config
-	if merged.Push["default"] != "simple" {
+	if merged.GetSection("push")["default"] != "simple" {
 		t.Error("Push.default should be inherited from global")
 	}
 
 	// Verify HTTP config
-	if merged.HTTP["postBuffer"] != "524288000" {
+	if merged.GetSection("http")["postBuffer"] != "524288000" {
 		t.Error("HTTP.postBuffer should be from profile")
 	}
 }
@@ -415,7 +419,7 @@ func TestLoadConfigInvalidYAML(t *testing.T) {
 		t.Fatalf("Failed to create invalid YAML: %v", err)
 	}
 
-	_, err = LoadConfig(configFile)
+	_, err = LoadConfig(configFile, "")
 	if err == nil {
 		t.Error("LoadConfig should fail for invalid YAML")
 	}
@@ -492,30 +496,18 @@ func TestDetermineCurrent(t *testing.T) {
 func TestDetermineCurrentDirectly(t *testing.T) {
 	t.Parallel()
 
+	tmpDir := t.TempDir()
+	gitConfigFile := filepath.Join(tmpDir, ".gitconfig")
+
 	cfg := NewConfig()
 
-	// Test with no profiles
-	cfg.determineCurrent()
+	// Test with no profiles and no git config
+	cfg.determineCurrent(gitConfigFile)
 
 	if cfg.Current != "" {
 		t.Errorf("Current should be empty with no profiles, got: %s", cfg.Current)
 	}
 
-	// Add a profile
-	cfg.Profiles["test"] = &Profile{
-		User: UserConfig{
-			Name:  "Test User",
-			Email: "test@example.com",
-		},
-	}
-
-	// Call determineCurrent - won't match unless git config actually has these values
-	// This tests the code path for non-matching profiles
-	cfg.determineCurrent()
-	// Current will be empty unless the system's actual git config matches
-	// We're just ensuring no panic/error occurs
-
-	// Add multiple profiles to test the matching loop
 	cfg.Profiles["work"] = &Profile{
 		User: UserConfig{
 			Name:  "Work User",
@@ -529,8 +521,30 @@ func TestDetermineCurrentDirectly(t *testing.T) {
 		},
 	}
 
-	cfg.determineCurrent()
-	// Again, just ensuring the function completes without error
+	// The identity is read through include directives
+	includeFile := filepath.Join(tmpDir, "identity")
+	if err := os.WriteFile(includeFile, []byte("[user]\n\tname = Personal User\n\temail = personal@example.com\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+
+	if err := os.WriteFile(gitConfigFile, []byte("[include]\n\tpath = identity\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	cfg.determineCurrent(gitConfigFile)
+
+	if cfg.Current != "personal" {
+		t.Errorf("Expected current profile 'personal', got: %s", cfg.Current)
+	}
+
+	// An identity matching no profile leaves Current unchanged
+	other := NewConfig()
+	other.Profiles["work"] = cfg.Profiles["work"]
+	other.determineCurrent(gitConfigFile)
+
+	if other.Current != "" {
+		t.Errorf("Current should be empty when no profile matches, got: %s", other.Current)
+	}
 }
 
 func TestMergeMapEdgeCases(t *testing.T) {
@@ -581,3 +595,422 @@ func TestMergeMapEdgeCases(t *testing.T) {
 		t.Error("Profile value should be included")
 	}
 }
+
+func TestSaveAndLoadSettings(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	cfg := NewConfig()
+	cfg.Settings.Mode = "block"
+
+	if err := cfg.SaveConfig(configFile); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	loadedCfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if loadedCfg.Settings.Mode != "block" {
+		t.Errorf("Expected mode 'block', got '%s'", loadedCfg.Settings.Mode)
+	}
+}
+
+func TestGlobalSections(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig()
+	cfg.Global = map[string]any{
+		"push":  map[string]any{"default": "simple"},
+		"Core":  map[string]any{"editor": "vim"},
+		"alias": map[string]any{"st": "status"},
+	}
+
+	want := []string{"alias", "core", "push"}
+	if got := cfg.GlobalSections(); !slices.Equal(got, want) {
+		t.Errorf("GlobalSections() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeMapLists(t *testing.T) {
+	t.Parallel()
+
+	global := map[string]any{
+		"directory": []any{"/srv/shared"},
+		"helper":    "cache",
+	}
+
+	// Lists replace inherited values by default
+	result := mergeMap(global, map[string]any{"directory": []any{"/srv/work"}})
+	if !slices.Equal(toList(result["directory"]), []any{"/srv/work"}) {
+		t.Errorf("Profile list should replace global list, got %v", result["directory"])
+	}
+
+	// A trailing '+' appends to the inherited list
+	result = mergeMap(global, map[string]any{
+		"directory+": []any{"/srv/work"},
+		"helper+":    []any{"", "store"},
+	})
+
+	if !slices.Equal(toList(result["directory"]), []any{"/srv/shared", "/srv/work"}) {
+		t.Errorf("Profile list should be appended to global list, got %v", result["directory"])
+	}
+
+	if !slices.Equal(toList(result["helper"]), []any{"cache", "", "store"}) {
+		t.Errorf("Scalar should be extended into a list, got %v", result["helper"])
+	}
+
+	if _, exists := result["directory+"]; exists {
+		t.Error("Append markers should not be kept in the merged result")
+	}
+
+	// Appending without an inherited value yields the profile list
+	result = mergeMap(nil, map[string]any{"directory+": []string{"/srv/a"}})
+	if !slices.Equal(toList(result["directory"]), []any{"/srv/a"}) {
+		t.Errorf("Append without inherited value should keep the list, got %v", result["directory"])
+	}
+}
+
+func TestMergeGlobalUser(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig()
+	cfg.Global = map[string]any{
+		"user": map[string]any{
+			"name":          "Shared Name",
+			"useConfigOnly": true,
+		},
+	}
+
+	if err := cfg.AddProfile("work", &Profile{User: UserConfig{Email: "work@example.com"}}); err != nil {
+		t.Fatalf("AddProfile failed: %v", err)
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if merged.User.Name != "Shared Name" || merged.User.Email != "work@example.com" {
+		t.Errorf("Global user keys should be merged with the profile, got %+v", merged.User)
+	}
+
+	if merged.User.Extra["useConfigOnly"] != true {
+		t.Errorf("Extra global user keys should be inherited, got %v", merged.User.Extra)
+	}
+
+	if merged.GetSection("user") != nil {
+		t.Error("The user section should not be duplicated in Sections")
+	}
+}
+
+func TestMergeMapDeep(t *testing.T) {
+	t.Parallel()
+
+	global := map[string]any{
+		"features":     "decorations",
+		"line-numbers": true,
+		"decorations": map[string]any{
+			"commit-style": "bold yellow",
+			"file-style":   "bold blue",
+		},
+	}
+
+	profile := map[string]any{
+		"features": "side-by-side",
+		"decorations": map[string]any{
+			"file-style": "bold green",
+		},
+	}
+
+	result := mergeMap(global, profile)
+
+	if result["features"] != "side-by-side" || result["line-numbers"] != true {
+		t.Errorf("Top-level keys should be merged, got %v", result)
+	}
+
+	decorations, ok := result["decorations"].(map[string]any)
+	if !ok {
+		t.Fatalf("Nested map should be kept, got %T", result["decorations"])
+	}
+
+	if decorations["commit-style"] != "bold yellow" || decorations["file-style"] != "bold green" {
+		t.Errorf("Nested maps should be merged key by key, got %v", decorations)
+	}
+
+	// The global map must not be modified by the merge
+	if global["decorations"].(map[string]any)["file-style"] != "bold blue" {
+		t.Error("mergeMap should not modify its inputs")
+	}
+}
+
+func TestMergeMapUnset(t *testing.T) {
+	t.Parallel()
+
+	global := map[string]any{
+		"pager":  "delta",
+		"editor": "vim",
+		"decorations": map[string]any{
+			"commit-style": "bold yellow",
+			"file-style":   "bold blue",
+		},
+		"interactive": map[string]any{
+			"keep-plus-minus-markers": false,
+		},
+	}
+
+	profile := map[string]any{
+		"pager":       nil,
+		"interactive": nil,
+		"decorations": map[string]any{
+			"file-style": nil,
+		},
+		"missing": nil,
+	}
+
+	result := mergeMap(global, profile)
+
+	for _, key := range []string{"pager", "interactive", "missing"} {
+		if _, exists := result[key]; exists {
+			t.Errorf("Key %q should be removed by a null value", key)
+		}
+	}
+
+	if result["editor"] != "vim" {
+		t.Error("Keys not unset should be inherited")
+	}
+
+	decorations, _ := result["decorations"].(map[string]any)
+	if _, exists := decorations["file-style"]; exists || decorations["commit-style"] != "bold yellow" {
+		t.Errorf("Nested null should remove only that key, got %v", decorations)
+	}
+}
+
+func TestMergeUnsetFromYAML(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `global:
+  core:
+    pager: delta
+    editor: vim
+  delta:
+    features: decorations
+    navigate: true
+  commit:
+    gpgsign: true
+profiles:
+  plain:
+    core:
+      pager: ~
+    delta:
+      features: side-by-side
+    commit: null
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	merged, err := cfg.Merge("plain")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	core := merged.GetSection("core")
+	if _, exists := core["pager"]; exists || core["editor"] != "vim" {
+		t.Errorf("core.pager should be unset and core.editor inherited, got %v", core)
+	}
+
+	delta := merged.GetSection("delta")
+	if delta["features"] != "side-by-side" || delta["navigate"] != true {
+		t.Errorf("delta should be merged key by key, got %v", delta)
+	}
+
+	if merged.GetSection("commit") != nil {
+		t.Error("A null section should drop the inherited section")
+	}
+}
+
+func TestMergeCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"autocrlf": "input", "editor": "vim"},
+			"http": map[string]any{
+				"https://Example.com": map[string]any{"sslVerify": true},
+			},
+		},
+		Profiles: map[string]*Profile{
+			"work": {
+				User: UserConfig{Name: "Work", Email: "work@example.com"},
+				Sections: map[string]any{
+					"Core": map[string]any{"autoCRLF": "false"},
+					"http": map[string]any{
+						"https://example.com": map[string]any{"sslVerify": false},
+					},
+				},
+			},
+		},
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	core := merged.GetSection("core")
+	if len(core) != 2 || core["autoCRLF"] != "false" || core["editor"] != "vim" {
+		t.Errorf("Section and key names should merge case-insensitively, got %v", merged.Sections)
+	}
+
+	// Subsection names are case-sensitive
+	if http := merged.GetSection("http"); len(http) != 2 {
+		t.Errorf("Subsections differing in case should be kept apart, got %v", http)
+	}
+}
+
+func TestMergeMapCaseInsensitiveUnset(t *testing.T) {
+	t.Parallel()
+
+	global := map[string]any{"pager": "less", "directory": []any{"/a"}}
+	profile := map[string]any{"Pager": nil, "Directory+": []any{"/b"}}
+
+	result := mergeMap(global, profile)
+
+	if _, exists := result["pager"]; exists {
+		t.Errorf("Unset should match keys case-insensitively, got %v", result)
+	}
+
+	if got, ok := result["Directory"].([]any); !ok || len(got) != 2 {
+		t.Errorf("Append should match keys case-insensitively, got %v", result)
+	}
+}
+
+func TestKeyCollisions(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{"core": map[string]any{"editor": "vim"}},
+		Profiles: map[string]*Profile{
+			"work": {
+				Sections: map[string]any{
+					"push": map[string]any{"autoSetupRemote": true, "autosetupremote": false},
+					"Core": map[string]any{"editor": "nano"},
+					"core": map[string]any{"pager": "less"},
+					"http": map[string]any{
+						"https://a": map[string]any{"sslVerify": true},
+						"https://A": map[string]any{"sslverify": true},
+					},
+				},
+			},
+			"clean": {},
+		},
+	}
+
+	warnings, err := cfg.KeyCollisions("work")
+	if err != nil {
+		t.Fatalf("KeyCollisions failed: %v", err)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 collisions, got %v", warnings)
+	}
+
+	if !strings.Contains(warnings[0], "'Core' and 'core'") ||
+		!strings.Contains(warnings[1], "'push.autoSetupRemote' and 'push.autosetupremote'") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+
+	if warnings, _ := cfg.KeyCollisions("clean"); len(warnings) != 0 {
+		t.Errorf("Expected no collisions, got %v", warnings)
+	}
+
+	if _, err := cfg.KeyCollisions("missing"); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
+
+func TestDetermineCurrentMarker(t *testing.T) {
+	t.Parallel()
+
+	// Both profiles share the identity and differ only in URL rewrites
+	newSharedConfig := func() *Config {
+		cfg := NewConfig()
+		cfg.Profiles["github"] = &Profile{
+			User: UserConfig{Name: "Me", Email: "me@example.com"},
+			URL:  []URLConfig{{Pattern: "ssh://git@github.com/", InsteadOf: "https://github.com/"}},
+		}
+		cfg.Profiles["gitlab"] = &Profile{
+			User: UserConfig{Name: "Me", Email: "me@example.com"},
+			URL:  []URLConfig{{Pattern: "ssh://git@gitlab.com/", InsteadOf: "https://gitlab.com/"}},
+		}
+
+		return cfg
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		current  string
+		modified bool
+	}{
+		{
+			name: "marker",
+			content: "[gitcontext]\n\tprofile = gitlab\n[user]\n\tname = Me\n\temail = me@example.com\n" +
+				"[url \"ssh://git@gitlab.com/\"]\n\tinsteadOf = https://gitlab.com/\n",
+			current: "gitlab",
+		},
+		{
+			name:     "marker with edited config",
+			content:  "[gitcontext]\n\tprofile = gitlab\n[user]\n\tname = Me\n\temail = me@example.com\n",
+			current:  "gitlab",
+			modified: true,
+		},
+		{
+			name: "no marker, other values decide",
+			content: "[user]\n\tname = Me\n\temail = me@example.com\n" +
+				"[url \"ssh://git@gitlab.com/\"]\n\tinsteadOf = https://gitlab.com/\n",
+			current: "gitlab",
+		},
+		{
+			name:     "no marker, identity only",
+			content:  "[user]\n\tname = Me\n\temail = me@example.com\n",
+			current:  "github",
+			modified: true,
+		},
+		{
+			name: "marker of a removed profile falls back to the identity",
+			content: "[gitcontext]\n\tprofile = removed\n[user]\n\tname = Me\n\temail = me@example.com\n" +
+				"[url \"ssh://git@github.com/\"]\n\tinsteadOf = https://github.com/\n",
+			current: "github",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gitConfigFile := filepath.Join(t.TempDir(), ".gitconfig")
+			if err := os.WriteFile(gitConfigFile, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write git config: %v", err)
+			}
+
+			cfg := newSharedConfig()
+			cfg.determineCurrent(gitConfigFile)
+
+			if cfg.Current != tt.current || cfg.Modified != tt.modified {
+				t.Errorf("determineCurrent() = (%q, modified %v), want (%q, modified %v)",
+					cfg.Current, cfg.Modified, tt.current, tt.modified)
+			}
+		})
+	}
+}
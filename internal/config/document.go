@@ -0,0 +1,785 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cockroachdb/errors"
+)
+
+// defaultIndent is the indentation of nested mappings in a file whose own
+// indentation is unknown, as written by yaml.Marshal.
+const defaultIndent = 4
+
+// errUnpatchable reports YAML that cannot be edited in place.
+var errUnpatchable = errors.New("yaml layout cannot be edited in place")
+
+// errRewrite reports a config.yaml left unchanged because it cannot be edited
+// in place, and rewriting it would lose its comments and layout.
+var errRewrite = errors.New("config file cannot be updated without rewriting it, which would lose its comments and layout")
+
+// document is config.yaml as authored. Saving a configuration loaded from it
+// only rewrites the entries whose values changed, so comments, blank lines
+// and the order of keys stay as they were.
+type document struct {
+	src    []byte
+	node   *yaml.Node // Document node
+	root   *yaml.Node // Top-level block mapping, nil when the file has none
+	lines  []int      // Offset of the start of each line
+	indent int        // Indentation step of nested mappings
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// parseDocument parses the content of config.yaml.
+func parseDocument(data []byte) (*document, error) {
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+
+	doc := &document{src: data, node: node, lines: []int{0}, indent: defaultIndent}
+
+	for i, c := range data {
+		if c == '\n' {
+			doc.lines = append(doc.lines, i+1)
+		}
+	}
+
+	if len(node.Content) == 1 && isBlockMapping(node.Content[0]) {
+		doc.root = node.Content[0]
+		doc.indent = nestedIndent(doc.root)
+	}
+
+	return doc, nil
+}
+
+// decode stores the values of the document in config.
+func (d *document) decode(config *Config) error {
+	if d.node.Kind == 0 {
+		// Empty file
+		return nil
+	}
+
+	return d.node.Decode(config)
+}
+
+// update returns the document holding the values of config. Only the
+// entries that changed are rewritten. A document that cannot be edited in
+// place is an error rather than written anew, which would drop its comments
+// and layout.
+func (d *document) update(config *Config) ([]byte, error) {
+	fresh := &yaml.Node{}
+	if err := fresh.Encode(config); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal config")
+	}
+
+	if d.root == nil {
+		return d.fill(fresh)
+	}
+
+	var edits []edit
+	if err := d.patchMapping(&edits, d.root, fresh); err != nil {
+		return nil, errors.WithSecondaryError(errors.WithStack(errRewrite), err)
+	}
+
+	data, err := d.apply(edits)
+	if err != nil {
+		return nil, errors.WithSecondaryError(errors.WithStack(errRewrite), err)
+	}
+
+	if !sameDocument(data, fresh) {
+		return nil, errors.WithStack(errRewrite)
+	}
+
+	return data, nil
+}
+
+// fill returns fresh as the content of a document without a top-level block
+// mapping. Only an empty document, or one holding nothing but comments,
+// which are kept above it, can be filled.
+func (d *document) fill(fresh *yaml.Node) ([]byte, error) {
+	if d.node.Kind != 0 {
+		return nil, errors.WithStack(errRewrite)
+	}
+
+	text, err := d.render(fresh, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	data := slices.Clone(d.src)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+
+	return append(data, text+"\n"...), nil
+}
+
+// patchMapping records the edits turning the block mapping orig into want.
+// Keys are matched by name: equal values are left alone, changed ones are
+// edited as narrowly as possible, missing ones are removed and new ones are
+// appended after the last entry.
+func (d *document) patchMapping(edits *[]edit, orig, want *yaml.Node) error {
+	for i := 0; i < len(orig.Content); i += 2 {
+		key, value := orig.Content[i], orig.Content[i+1]
+		wanted := mappingValue(want, key.Value)
+
+		var err error
+
+		switch {
+		case wanted == nil:
+			if isBlank(value) {
+				// Empty values are omitted when marshaling, removing them would only reformat
+				continue
+			}
+
+			err = d.removeEntry(edits, key, value)
+		case sameValue(value, wanted):
+			continue
+		default:
+			err = d.patchValue(edits, key, value, wanted)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var added []*yaml.Node
+
+	for i := 0; i < len(want.Content); i += 2 {
+		key, value := want.Content[i], want.Content[i+1]
+		if mappingValue(orig, key.Value) == nil && !isEmpty(value) && !isBlank(value) {
+			added = append(added, key, value)
+		}
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	return d.appendEntries(edits, orig, added)
+}
+
+// patchValue records the edits turning the value of an entry into want.
+func (d *document) patchValue(edits *[]edit, key, value, want *yaml.Node) error {
+	switch {
+	case isBlockMapping(value) && want.Kind == yaml.MappingNode && keepsEntries(value, want):
+		return d.patchMapping(edits, value, want)
+	case value.Kind == yaml.ScalarNode && want.Kind == yaml.ScalarNode && isInlineScalar(value):
+		style := value.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle)
+
+		text, err := d.render(&yaml.Node{Kind: yaml.ScalarNode, Tag: want.Tag, Value: want.Value, Style: style}, 0)
+		if err != nil || strings.Contains(text, "\n") {
+			return d.replaceEntry(edits, key, value, want)
+		}
+
+		return d.replace(edits, key, value, text)
+	case value.Style&yaml.FlowStyle != 0 && len(value.Content) > 0 && want.Kind == value.Kind && len(want.Content) > 0:
+		text, err := d.render(flowStyle(want), 0)
+		if err != nil || strings.Contains(text, "\n") {
+			return d.replaceEntry(edits, key, value, want)
+		}
+
+		return d.replace(edits, key, value, text)
+	default:
+		return d.replaceEntry(edits, key, value, want)
+	}
+}
+
+// replace records the replacement of the text of the value node of key.
+func (d *document) replace(edits *[]edit, key, node *yaml.Node, text string) error {
+	end, err := d.end(node, key.Column-1)
+	if err != nil {
+		return err
+	}
+
+	*edits = append(*edits, edit{start: d.offset(node), end: end, text: text})
+
+	return nil
+}
+
+// replaceEntry records the replacement of a whole entry, up to the end of
+// its last line.
+func (d *document) replaceEntry(edits *[]edit, key, value, want *yaml.Node) error {
+	end, err := d.entryEnd(key, value)
+	if err != nil {
+		return err
+	}
+
+	text, err := d.render(entryNode(key, want), key.Column-1)
+	if err != nil {
+		return err
+	}
+
+	*edits = append(*edits, edit{start: d.offset(key), end: d.lineEnd(end), text: text})
+
+	return nil
+}
+
+// removeEntry records the removal of the lines of an entry, along with the
+// comment above it.
+func (d *document) removeEntry(edits *[]edit, key, value *yaml.Node) error {
+	start := d.lines[key.Line-1]
+	if strings.TrimSpace(string(d.src[start:d.offset(key)])) != "" {
+		// The entry shares its line, as the first entry of a sequence item does
+		return errUnpatchable
+	}
+
+	end, err := d.entryEnd(key, value)
+	if err != nil {
+		return err
+	}
+
+	*edits = append(*edits, edit{start: d.commentStart(key), end: min(d.lineEnd(end)+1, len(d.src))})
+
+	return nil
+}
+
+// commentStart returns the offset of the start of the line of key, or of the
+// comment lines right above it that document it.
+func (d *document) commentStart(key *yaml.Node) int {
+	start := d.lines[key.Line-1]
+
+	if key.HeadComment != "" {
+		for line, n := key.Line-1, strings.Count(key.HeadComment, "\n")+1; line > 0 && n > 0; line, n = line-1, n-1 {
+			text := strings.TrimSpace(string(d.src[d.lines[line-1]:d.lines[line]]))
+			if !strings.HasPrefix(text, "#") {
+				break
+			}
+
+			start = d.lines[line-1]
+		}
+	}
+
+	return start
+}
+
+// appendEntries records the insertion of entries after the last entry of
+// the block mapping orig, separated by a blank line when its entries are.
+func (d *document) appendEntries(edits *[]edit, orig *yaml.Node, entries []*yaml.Node) error {
+	count := len(orig.Content)
+
+	end, err := d.entryEnd(orig.Content[count-2], orig.Content[count-1])
+	if err != nil {
+		return err
+	}
+
+	column := orig.Content[0].Column - 1
+
+	text, err := d.render(&yaml.Node{Kind: yaml.MappingNode, Content: entries}, column)
+	if err != nil {
+		return err
+	}
+
+	text = strings.Repeat(" ", column) + text
+
+	if count >= 4 {
+		previous, err := d.entryEnd(orig.Content[count-4], orig.Content[count-3])
+		if err != nil {
+			return err
+		}
+
+		if hasBlankLine(d.src[d.lineEnd(previous):d.lines[orig.Content[count-2].Line-1]]) {
+			text = "\n" + text
+		}
+	}
+
+	at := d.lineEnd(end)
+	if at == len(d.src) {
+		text = "\n" + text
+	} else {
+		at++
+		text += "\n"
+	}
+
+	*edits = append(*edits, edit{start: at, end: at, text: text})
+
+	return nil
+}
+
+// apply returns the source with the edits made.
+func (d *document) apply(edits []edit) ([]byte, error) {
+	// Entries are appended once their mapping is patched, so stable sorting
+	// keeps nested insertions ahead of those at the same offset
+	slices.SortStableFunc(edits, func(a, b edit) int {
+		return a.start - b.start
+	})
+
+	var buf bytes.Buffer
+
+	last := 0
+
+	for _, e := range edits {
+		if e.start < last {
+			return nil, errUnpatchable
+		}
+
+		buf.Write(d.src[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+
+	buf.Write(d.src[last:])
+
+	return buf.Bytes(), nil
+}
+
+// render encodes node with the document indentation, without the final
+// newline. Lines after the first are indented by column, so the text can
+// start at that column.
+func (d *document) render(node *yaml.Node, column int) (string, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+
+	if err := encoder.Encode(node); err != nil {
+		return "", errors.Wrap(err, "failed to marshal config")
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to marshal config")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", column) + lines[i]
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// offset returns the offset of the first character of node.
+func (d *document) offset(node *yaml.Node) int {
+	offset := d.lines[node.Line-1]
+
+	// Columns count characters, not bytes
+	for i := 1; i < node.Column && offset < len(d.src) && d.src[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRune(d.src[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
+// lineEnd returns the offset of the end of the line holding offset.
+func (d *document) lineEnd(offset int) int {
+	if i := bytes.IndexByte(d.src[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+
+	return len(d.src)
+}
+
+// entryEnd returns the offset following the text of an entry.
+func (d *document) entryEnd(key, value *yaml.Node) (int, error) {
+	if isImplicitNull(value) {
+		// A key without value, the entry ends with the key
+		return d.end(key, key.Column-1)
+	}
+
+	return d.end(value, key.Column-1)
+}
+
+// end returns the offset following the text of node, whose parent entry is
+// indented by indent.
+func (d *document) end(node *yaml.Node, indent int) (int, error) {
+	start := d.offset(node)
+
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		if node.Style&yaml.FlowStyle != 0 {
+			return d.flowEnd(start)
+		}
+
+		count := len(node.Content)
+		if count == 0 {
+			return 0, errUnpatchable
+		}
+
+		if node.Kind == yaml.MappingNode {
+			return d.entryEnd(node.Content[count-2], node.Content[count-1])
+		}
+
+		return d.end(node.Content[count-1], node.Column-1)
+	case yaml.AliasNode:
+		if d.src[start] != '*' {
+			return 0, errUnpatchable
+		}
+
+		return start + 1 + len(node.Value), nil
+	case yaml.ScalarNode:
+		switch {
+		case node.Style&yaml.DoubleQuotedStyle != 0:
+			return d.quotedEnd(start, '"')
+		case node.Style&yaml.SingleQuotedStyle != 0:
+			return d.quotedEnd(start, '\'')
+		case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+			return d.blockScalarEnd(start, indent), nil
+		default:
+			return d.plainEnd(start, indent, node.Value)
+		}
+	}
+
+	return 0, errUnpatchable
+}
+
+// plainEnd returns the offset following a plain scalar, whose parent entry
+// is indented by indent. The scalar continues on the lines indented deeper,
+// up to a comment.
+func (d *document) plainEnd(start, indent int, value string) (int, error) {
+	text, commented := uncomment(string(d.src[start:d.lineEnd(start)]))
+	end := start + len(text)
+	lines := []string{text}
+
+	for offset := d.lineEnd(start) + 1; !commented && offset < len(d.src); {
+		lineEnd := d.lineEnd(offset)
+		line := string(d.src[offset:lineEnd])
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.TrimSpace(trimmed) == "" {
+			lines = append(lines, "")
+			offset = lineEnd + 1
+
+			continue
+		}
+
+		if len(line)-len(trimmed) <= indent || strings.HasPrefix(trimmed, "#") {
+			break
+		}
+
+		text, commented = uncomment(trimmed)
+		end = offset + len(line) - len(trimmed) + len(text)
+		lines = append(lines, text)
+		offset = lineEnd + 1
+	}
+
+	if fold(lines) != value {
+		return 0, errUnpatchable
+	}
+
+	return end, nil
+}
+
+// uncomment returns a line of a plain scalar without the comment following
+// it, and whether there was one.
+func uncomment(line string) (string, bool) {
+	commented := false
+
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			line, commented = line[:i], true
+
+			break
+		}
+	}
+
+	return strings.TrimRight(line, " \t\r"), commented
+}
+
+// fold joins the lines of a plain scalar as YAML does: a line break becomes
+// a space, and each blank line a newline.
+func fold(lines []string) string {
+	var buf strings.Builder
+
+	buf.WriteString(lines[0])
+
+	breaks := 0
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			breaks++
+
+			continue
+		}
+
+		if breaks == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteString(strings.Repeat("\n", breaks))
+		}
+
+		buf.WriteString(line)
+
+		breaks = 0
+	}
+
+	return buf.String()
+}
+
+// quotedEnd returns the offset following a scalar quoted with quote.
+func (d *document) quotedEnd(start int, quote byte) (int, error) {
+	if d.src[start] != quote {
+		return 0, errUnpatchable
+	}
+
+	for i := start + 1; i < len(d.src); i++ {
+		switch {
+		case quote == '"' && d.src[i] == '\\':
+			i++
+		case d.src[i] != quote:
+		case quote == '\'' && i+1 < len(d.src) && d.src[i+1] == '\'':
+			i++
+		default:
+			return i + 1, nil
+		}
+	}
+
+	return 0, errUnpatchable
+}
+
+// blockScalarEnd returns the offset following the last line of a literal or
+// folded scalar, the lines indented beyond its entry.
+func (d *document) blockScalarEnd(start, indent int) int {
+	end := d.lineEnd(start)
+
+	for offset := end + 1; offset < len(d.src); {
+		lineEnd := d.lineEnd(offset)
+		line := string(d.src[offset:lineEnd])
+
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(trimmed) != "" {
+			if len(line)-len(trimmed) <= indent {
+				break
+			}
+
+			end = lineEnd
+		}
+
+		offset = lineEnd + 1
+	}
+
+	return end
+}
+
+// flowEnd returns the offset following a flow mapping or sequence.
+func (d *document) flowEnd(start int) (int, error) {
+	depth := 0
+
+	for i := start; i < len(d.src); i++ {
+		switch c := d.src[i]; c {
+		case '"', '\'':
+			// Quotes only start a scalar, they may appear within plain ones
+			if i > start && !strings.ContainsRune(" \t\n{[,:", rune(d.src[i-1])) {
+				continue
+			}
+
+			end, err := d.quotedEnd(i, c)
+			if err != nil {
+				return 0, err
+			}
+
+			i = end - 1
+		case '#':
+			if strings.ContainsRune(" \t\n", rune(d.src[i-1])) {
+				i = d.lineEnd(i)
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+
+	return 0, errUnpatchable
+}
+
+// sameDocument reports whether data holds the values of want.
+func sameDocument(data []byte, want *yaml.Node) bool {
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil || len(node.Content) != 1 {
+		return false
+	}
+
+	return sameValue(node.Content[0], want)
+}
+
+// sameValue reports whether two nodes hold the same value. Null, empty
+// strings, empty collections and missing keys are alike, as they are once
+// loaded.
+func sameValue(a, b *yaml.Node) bool {
+	var first, second any
+
+	if err := a.Decode(&first); err != nil {
+		return false
+	}
+
+	if err := b.Decode(&second); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(normalizeValue(first), normalizeValue(second))
+}
+
+// normalizeValue replaces empty strings and collections in value with nil
+// and drops the mapping entries left without a value.
+func normalizeValue(value any) any {
+	switch value := value.(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+	case map[string]any:
+		normalized := make(map[string]any, len(value))
+
+		for key, entry := range value {
+			if entry = normalizeValue(entry); entry != nil {
+				normalized[key] = entry
+			}
+		}
+
+		if len(normalized) == 0 {
+			return nil
+		}
+
+		return normalized
+	case []any:
+		if len(value) == 0 {
+			return nil
+		}
+
+		normalized := make([]any, len(value))
+		for i, entry := range value {
+			normalized[i] = normalizeValue(entry)
+		}
+
+		return normalized
+	}
+
+	return value
+}
+
+// mappingValue returns the value of key in a mapping node, nil when unset.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(mapping, key)
+
+	return value
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node, nil
+// when unset.
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+// keepsEntries reports whether patching orig into want leaves at least one
+// entry, a block mapping cannot be emptied in place.
+func keepsEntries(orig, want *yaml.Node) bool {
+	for i := 0; i < len(want.Content); i += 2 {
+		if mappingValue(orig, want.Content[i].Value) != nil || !isEmpty(want.Content[i+1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entryNode returns a mapping holding a single entry, without the comments
+// of key which stay in place.
+func entryNode(key, value *yaml.Node) *yaml.Node {
+	bare := *key
+	bare.HeadComment, bare.LineComment, bare.FootComment = "", "", ""
+
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{&bare, value}}
+}
+
+// flowStyle returns a copy of node written in flow style.
+func flowStyle(node *yaml.Node) *yaml.Node {
+	flow := *node
+	flow.Content = make([]*yaml.Node, len(node.Content))
+
+	for i, child := range node.Content {
+		flow.Content[i] = flowStyle(child)
+	}
+
+	if flow.Kind == yaml.MappingNode || flow.Kind == yaml.SequenceNode {
+		flow.Style |= yaml.FlowStyle
+	}
+
+	return &flow
+}
+
+// nestedIndent returns the indentation step of the first nested mapping.
+func nestedIndent(root *yaml.Node) int {
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if isBlockMapping(value) && len(value.Content) > 0 && value.Content[0].Column > key.Column {
+			return value.Content[0].Column - key.Column
+		}
+	}
+
+	return defaultIndent
+}
+
+// isBlockMapping reports whether node is a mapping written in block style.
+func isBlockMapping(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0
+}
+
+// isInlineScalar reports whether node is a scalar written on its key's line.
+func isInlineScalar(node *yaml.Node) bool {
+	return node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 && !isImplicitNull(node)
+}
+
+// isImplicitNull reports whether node is the missing value of a key.
+func isImplicitNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null" && node.Value == "" && node.Style == 0
+}
+
+// isEmpty reports whether node is null or an empty collection.
+func isEmpty(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode {
+		return node.Tag == "!!null"
+	}
+
+	return (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) == 0
+}
+
+// isBlank reports whether node is an empty string or collection, which
+// marshaling omits.
+func isBlank(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode {
+		return node.Tag == "!!str" && node.Value == ""
+	}
+
+	return (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) == 0
+}
+
+// hasBlankLine reports whether text holds a line of whitespace only.
+func hasBlankLine(text []byte) bool {
+	lines := strings.Split(string(text), "\n")
+	if len(lines) < 3 {
+		return false
+	}
+
+	// The first and last parts are the ends of the surrounding lines
+	for _, line := range lines[1 : len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			return true
+		}
+	}
+
+	return false
+}
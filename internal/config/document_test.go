@@ -0,0 +1,405 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+const authoredConfig = `# git-context profiles
+version: 1
+settings:
+  mode: include   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+profiles:
+  # Day job
+  work:
+    user: {name: Jane Doe, email: jane@work.example}
+    directories:
+      - ~/work
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@home.example' # public address
+`
+
+func TestSaveConfigPreservesLayout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		source string
+		edit   func(cfg *Config)
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			source: authoredConfig,
+			edit:   func(cfg *Config) {},
+			want:   authoredConfig,
+		},
+		{
+			name:   "changed scalar keeps its style and comment",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				cfg.Profiles["personal"].User.Email = "jane@example.org"
+				cfg.Settings.Mode = "block"
+			},
+			want: `# git-context profiles
+version: 1
+settings:
+  mode: block   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+profiles:
+  # Day job
+  work:
+    user: {name: Jane Doe, email: jane@work.example}
+    directories:
+      - ~/work
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@example.org' # public address
+`,
+		},
+		{
+			name:   "changed flow mapping stays on its line",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				cfg.Profiles["work"].User.Email = "jane@corp.example"
+			},
+			want: `# git-context profiles
+version: 1
+settings:
+  mode: include   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+profiles:
+  # Day job
+  work:
+    user: {name: Jane Doe, email: jane@corp.example}
+    directories:
+      - ~/work
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@home.example' # public address
+`,
+		},
+		{
+			name:   "changed list is rewritten",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				cfg.Profiles["work"].Directories = append(cfg.Profiles["work"].Directories, "~/clients")
+			},
+			want: `# git-context profiles
+version: 1
+settings:
+  mode: include   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+profiles:
+  # Day job
+  work:
+    user: {name: Jane Doe, email: jane@work.example}
+    directories:
+      - ~/work
+      - ~/clients
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@home.example' # public address
+`,
+		},
+		{
+			name:   "added profile is appended",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				cfg.Profiles["oss"] = &Profile{User: UserConfig{Name: "Jane", Email: "jane@oss.example"}}
+			},
+			want: authoredConfig + `
+  oss:
+    user:
+      name: Jane
+      email: jane@oss.example
+`,
+		},
+		{
+			name:   "removed profile goes with its comment",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				delete(cfg.Profiles, "work")
+			},
+			want: `# git-context profiles
+version: 1
+settings:
+  mode: include   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+profiles:
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@home.example' # public address
+`,
+		},
+		{
+			name:   "added global section",
+			source: authoredConfig,
+			edit: func(cfg *Config) {
+				cfg.Global["pull"] = map[string]any{"rebase": true}
+			},
+			want: `# git-context profiles
+version: 1
+settings:
+  mode: include   # keep my own gitconfig
+
+global:
+  core:
+    editor: vim
+
+  # URL rewrites shared by every profile
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+
+  pull:
+    rebase: true
+
+profiles:
+  # Day job
+  work:
+    user: {name: Jane Doe, email: jane@work.example}
+    directories:
+      - ~/work
+
+  # Side projects
+  personal:
+    user:
+      name: Jane Doe
+      email: 'jane@home.example' # public address
+`,
+		},
+		{
+			name:   "empty mapping is filled in block style",
+			source: "version: 1\nglobal: {}\nprofiles: {}\n",
+			edit: func(cfg *Config) {
+				cfg.Profiles["work"] = &Profile{User: UserConfig{Name: "Jane", Email: "jane@work.example"}}
+			},
+			want: "version: 1\nglobal: {}\nprofiles:\n    work:\n        user:\n            name: Jane\n            email: jane@work.example\n",
+		},
+		{
+			name:   "last profile removed",
+			source: "version: 1\nglobal: {}\nprofiles:\n  # Only one\n  work:\n    user:\n      name: Jane\n      email: jane@work.example\n",
+			edit: func(cfg *Config) {
+				delete(cfg.Profiles, "work")
+			},
+			want: "version: 1\nglobal: {}\nprofiles: {}\n",
+		},
+		{
+			name: "changed alias is replaced by its value",
+			source: `version: 1
+global: {}
+profiles:
+  base: &identity
+    user:
+      name: Jane
+      email: jane@work.example
+  work: *identity
+`,
+			edit: func(cfg *Config) {
+				cfg.Profiles["work"].User.Email = "jane@corp.example"
+			},
+			want: `version: 1
+global: {}
+profiles:
+  base: &identity
+    user:
+      name: Jane
+      email: jane@work.example
+  work:
+    user:
+      name: Jane
+      email: jane@corp.example
+`,
+		},
+		{
+			name: "changed multi-line scalar keeps its comments",
+			source: `version: 1
+global: {}
+profiles:
+  work:
+    user:
+      # Legal name
+      name: Jane
+        Doe # as on the contract
+      email: jane@work.example
+`,
+			edit: func(cfg *Config) {
+				cfg.Profiles["work"].User.Name = "Jane Q. Doe"
+			},
+			want: `version: 1
+global: {}
+profiles:
+  work:
+    user:
+      # Legal name
+      name: Jane Q. Doe # as on the contract
+      email: jane@work.example
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFile := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configFile, []byte(tt.source), 0o644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			cfg, err := LoadConfig(configFile, "")
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+
+			tt.edit(cfg)
+
+			if err := cfg.SaveConfig(configFile); err != nil {
+				t.Fatalf("SaveConfig failed: %v", err)
+			}
+
+			data, err := os.ReadFile(configFile)
+			if err != nil {
+				t.Fatalf("Failed to read config: %v", err)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("Saved config:\n%s\nwant:\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveConfigRefusesRewrite(t *testing.T) {
+	t.Parallel()
+
+	source := "# Written by hand\n{version: 1, global: {}, profiles: {work: {user: {name: Jane, email: jane@work.example}}}}\n"
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(source), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	cfg.Profiles["work"].User.Email = "jane@corp.example"
+
+	if err := cfg.SaveConfig(configFile); !errors.Is(err, errRewrite) {
+		t.Fatalf("Expected errRewrite, got %v", err)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	if string(data) != source {
+		t.Errorf("Config file changed:\n%s", data)
+	}
+}
+
+func TestSaveConfigTwice(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(authoredConfig), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	cfg.Settings.Mode = "block"
+	if err := cfg.SaveConfig(configFile); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	cfg.Settings.Mode = "overwrite"
+	if err := cfg.SaveConfig(configFile); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	loaded, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if loaded.Settings.Mode != "overwrite" {
+		t.Errorf("Expected mode 'overwrite', got '%s'", loaded.Settings.Mode)
+	}
+
+	if loaded.Profiles["personal"].User.Email != "jane@home.example" {
+		t.Errorf("Unexpected email '%s'", loaded.Profiles["personal"].User.Email)
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// SetValue sets a git key in global (target GlobalLayer) or in a profile,
+// the way it would be written by hand in config.yaml. A single value is
+// stored as a scalar, several as a list. Existing sections and keys are
+// reused even when spelled with a different case.
+func (c *Config) SetValue(target, key string, values []string) error {
+	section, subsection, name, ok := git.SplitKey(key)
+	if !ok {
+		return errors.WithStack(errors.Newf("invalid config key '%s'", key))
+	}
+
+	sections, err := c.targetSections(target)
+	if err != nil {
+		return err
+	}
+
+	section = strings.ToLower(section)
+
+	switch {
+	case section == "user" && subsection == "":
+		c.setUserValue(target, name, yamlValue(values))
+	case section == "url" && subsection != "" && strings.EqualFold(name, "insteadOf"):
+		c.setURLs(target, subsection, values)
+	default:
+		setSectionValue(sections, section, subsection, name, yamlValue(values))
+	}
+
+	return nil
+}
+
+// UnsetValue removes a git key from global or a profile. When a profile
+// still inherits the key afterwards, it is unset explicitly with null.
+// Inherited user name, email, signing key and url rewrites cannot be unset
+// that way and are only removed from the profile.
+func (c *Config) UnsetValue(target, key string) error {
+	if err := c.RemoveValue(target, key); err != nil {
+		return err
+	}
+
+	if target == GlobalLayer {
+		return nil
+	}
+
+	merged, err := c.Merge(target)
+	if err != nil {
+		return err
+	}
+
+	if _, inherited := git.ConfigValues(merged.GitConfig())[git.CanonicalKey(key)]; inherited {
+		c.setUnset(target, key)
+	}
+
+	return nil
+}
+
+// setUnset unsets an inherited key in a profile with an explicit null.
+func (c *Config) setUnset(target, key string) {
+	section, subsection, name, _ := git.SplitKey(key)
+	section = strings.ToLower(section)
+
+	switch {
+	case section == "url" && subsection != "" && strings.EqualFold(name, "insteadOf"):
+		// Profile url rewrites replace the inherited ones as a whole
+	case section != "user" || subsection != "":
+		setSectionValue(c.Profiles[target].Sections, section, subsection, name, nil)
+	default:
+		if !isTypedUserKey(name) {
+			user := &c.Profiles[target].User
+			if user.Extra == nil {
+				user.Extra = make(map[string]any)
+			}
+
+			user.Extra[name] = nil
+		}
+	}
+}
+
+// RemoveValue deletes a git key from global or a profile, without unsetting
+// a value the profile inherits.
+func (c *Config) RemoveValue(target, key string) error {
+	section, subsection, name, ok := git.SplitKey(key)
+	if !ok {
+		return errors.WithStack(errors.Newf("invalid config key '%s'", key))
+	}
+
+	sections, err := c.targetSections(target)
+	if err != nil {
+		return err
+	}
+
+	section = strings.ToLower(section)
+
+	switch {
+	case section == "user" && subsection == "":
+		c.setUserValue(target, name, nil)
+	case section == "url" && subsection != "" && strings.EqualFold(name, "insteadOf"):
+		c.setURLs(target, subsection, nil)
+	default:
+		if values := findSectionValues(sections, section, subsection, false); values != nil {
+			if existing := findKey(values, name, true); existing != "" {
+				delete(values, existing)
+			}
+		}
+
+		pruneEmptySections(sections)
+	}
+
+	return nil
+}
+
+// pruneEmptySections deletes the sections and nested subsections left
+// without keys.
+func pruneEmptySections(sections map[string]any) {
+	for name, value := range sections {
+		values, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for subsection, nested := range values {
+			if nested, ok := nested.(map[string]any); ok && len(nested) == 0 {
+				delete(values, subsection)
+			}
+		}
+
+		if len(values) == 0 {
+			delete(sections, name)
+		}
+	}
+}
+
+// targetSections returns the section map of global or of a profile.
+func (c *Config) targetSections(target string) (map[string]any, error) {
+	if target == GlobalLayer {
+		if c.Global == nil {
+			c.Global = make(map[string]any)
+		}
+
+		return c.Global, nil
+	}
+
+	profile, err := c.GetProfile(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.Sections == nil {
+		profile.Sections = make(map[string]any)
+	}
+
+	return profile.Sections, nil
+}
+
+// typedUserKeys are the user.* keys stored in UserConfig fields.
+var typedUserKeys = map[string]struct{}{"name": {}, "email": {}, "signingkey": {}}
+
+// setUserValue sets or, with a nil value, removes a user.* key.
+func (c *Config) setUserValue(target, name string, value any) {
+	if target == GlobalLayer {
+		values := findSectionValues(c.Global, "user", "", value != nil)
+		if values == nil {
+			return
+		}
+
+		existing := findKey(values, name, true)
+		if value == nil {
+			delete(values, existing)
+		} else if existing != "" {
+			values[existing] = value
+		} else {
+			values[name] = value
+		}
+
+		return
+	}
+
+	profile := c.Profiles[target]
+	str, _ := value.(string)
+
+	switch strings.ToLower(name) {
+	case "name":
+		profile.User.Name = str
+	case "email":
+		profile.User.Email = str
+	case "signingkey":
+		profile.User.SigningKey = str
+	default:
+		if profile.User.Extra == nil {
+			profile.User.Extra = make(map[string]any)
+		}
+
+		existing := findKey(profile.User.Extra, name, true)
+		if value == nil {
+			delete(profile.User.Extra, existing)
+		} else if existing != "" {
+			profile.User.Extra[existing] = value
+		} else {
+			profile.User.Extra[name] = value
+		}
+	}
+}
+
+// setURLs replaces the url rewrites of pattern with one per insteadOf value.
+func (c *Config) setURLs(target, pattern string, insteadOf []string) {
+	var urls []URLConfig
+	if target == GlobalLayer {
+		urls = globalURLs(c.Global)
+	} else {
+		urls = c.Profiles[target].URL
+	}
+
+	urls = slices.DeleteFunc(slices.Clone(urls), func(u URLConfig) bool {
+		return u.Pattern == pattern
+	})
+
+	for _, value := range insteadOf {
+		urls = append(urls, URLConfig{Pattern: pattern, InsteadOf: value})
+	}
+
+	if target != GlobalLayer {
+		c.Profiles[target].URL = urls
+
+		return
+	}
+
+	if len(urls) == 0 {
+		delete(c.Global, "url")
+	} else {
+		c.Global["url"] = urls
+	}
+}
+
+// setSectionValue sets name in a section, creating the section if needed.
+// A subsection is stored in an existing nested map if there is one, or else
+// with the quoted `section "subsection"` form.
+func setSectionValue(sections map[string]any, section, subsection, name string, value any) {
+	values := findSectionValues(sections, section, subsection, true)
+
+	// Keep the spelling already used in config.yaml
+	if existing := findKey(values, name, true); existing != "" {
+		name = existing
+	}
+
+	values[name] = value
+}
+
+// findSectionValues returns the map holding the keys of a section or
+// subsection, matching section names case-insensitively. With create set,
+// missing maps are created; otherwise nil is returned for them.
+func findSectionValues(sections map[string]any, section, subsection string, create bool) map[string]any {
+	var sectionKey string
+
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		if canonicalSectionName(name) == section {
+			sectionKey = name
+
+			break
+		}
+	}
+
+	if subsection == "" {
+		values, ok := sections[sectionKey].(map[string]any)
+		if !ok && create {
+			values = make(map[string]any)
+			sections[section] = values
+		}
+
+		return values
+	}
+
+	// A nested map under the section
+	if parent, ok := sections[sectionKey].(map[string]any); ok {
+		if values, ok := parent[subsection].(map[string]any); ok {
+			return values
+		}
+	}
+
+	// The quoted form
+	quoted := section + ` "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection) + `"`
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		if canonicalSectionName(name) == quoted {
+			if values, ok := sections[name].(map[string]any); ok {
+				return values
+			}
+		}
+	}
+
+	if !create {
+		return nil
+	}
+
+	values := make(map[string]any)
+	sections[quoted] = values
+
+	return values
+}
+
+// yamlValue converts git values into the value stored in config.yaml.
+func yamlValue(values []string) any {
+	converted := make([]any, len(values))
+	for i, value := range values {
+		switch value {
+		case "true":
+			converted[i] = true
+		case "false":
+			converted[i] = false
+		default:
+			converted[i] = value
+		}
+	}
+
+	if len(converted) == 1 {
+		return converted[0]
+	}
+
+	return converted
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetValue(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "vim"},
+		},
+		Profiles: map[string]*Profile{
+			"work": {
+				Sections: map[string]any{
+					"push": map[string]any{"autoSetupRemote": true},
+					"http": map[string]any{
+						"https://corp.example.com": map[string]any{"sslVerify": false},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		target string
+		key    string
+		values []string
+	}{
+		{"work", "push.autosetupremote", []string{"false"}},
+		{"work", "pull.rebase", []string{"true"}},
+		{"work", "user.email", []string{"new@example.com"}},
+		{"work", "user.useconfigonly", []string{"true"}},
+		{"work", "http.https://corp.example.com.sslverify", []string{"true"}},
+		{"work", "credential.https://github.com.helper", []string{"", "store"}},
+		{"work", "url.ssh://git@github.com/.insteadof", []string{"https://github.com/", "gh:"}},
+		{GlobalLayer, "core.editor", []string{"nano"}},
+		{GlobalLayer, "user.name", []string{"Global User"}},
+	}
+
+	for _, tt := range tests {
+		if err := cfg.SetValue(tt.target, tt.key, tt.values); err != nil {
+			t.Fatalf("SetValue(%q, %q) failed: %v", tt.target, tt.key, err)
+		}
+	}
+
+	work := cfg.Profiles["work"]
+
+	// Existing keys are reused whatever their case
+	if push := work.GetSection("push"); len(push) != 1 || push["autoSetupRemote"] != false {
+		t.Errorf("Expected push.autoSetupRemote to be updated in place, got %v", push)
+	}
+
+	if work.GetSection("pull")["rebase"] != true {
+		t.Errorf("Expected pull.rebase to be added, got %v", work.GetSection("pull"))
+	}
+
+	if work.User.Email != "new@example.com" || work.User.Extra["useconfigonly"] != true {
+		t.Errorf("Unexpected user section: %+v", work.User)
+	}
+
+	// Existing nested subsections are reused, new ones use the quoted form
+	nested, _ := work.GetSection("http")["https://corp.example.com"].(map[string]any)
+	if nested["sslVerify"] != true {
+		t.Errorf("Expected the nested subsection to be updated, got %v", work.GetSection("http"))
+	}
+
+	helpers, _ := work.GetSection(`credential "https://github.com"`)["helper"].([]any)
+	if !slices.Equal(helpers, []any{"", "store"}) {
+		t.Errorf("Expected a list of credential helpers, got %v", work.Sections)
+	}
+
+	if len(work.URL) != 2 || work.URL[1].InsteadOf != "gh:" {
+		t.Errorf("Expected two url rewrites, got %v", work.URL)
+	}
+
+	merged := cfg.MergeGlobal()
+	if merged.GetSection("core")["editor"] != "nano" || merged.User.Name != "Global User" {
+		t.Errorf("Expected global values to be set, got %+v", merged)
+	}
+
+	if err := cfg.SetValue("missing", "core.editor", []string{"vim"}); err == nil {
+		t.Error("SetValue should fail for a missing profile")
+	}
+
+	if err := cfg.SetValue("work", "invalid", []string{"x"}); err == nil {
+		t.Error("SetValue should fail for an invalid key")
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "vim", "pager": "less"},
+		},
+		Profiles: map[string]*Profile{
+			"work": {
+				Sections: map[string]any{"push": map[string]any{"autoSetupRemote": true}},
+			},
+		},
+	}
+
+	// Keys defined by the profile are removed
+	if err := cfg.UnsetValue("work", "push.autoSetupRemote"); err != nil {
+		t.Fatalf("UnsetValue failed: %v", err)
+	}
+
+	if _, exists := cfg.Profiles["work"].GetSection("push")["autoSetupRemote"]; exists {
+		t.Error("push.autoSetupRemote should be removed from the profile")
+	}
+
+	// Inherited keys are unset explicitly
+	if err := cfg.UnsetValue("work", "core.pager"); err != nil {
+		t.Fatalf("UnsetValue failed: %v", err)
+	}
+
+	core := cfg.Profiles["work"].GetSection("core")
+	if value, exists := core["pager"]; !exists || value != nil {
+		t.Errorf("core.pager should be unset with null, got %v", core)
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if _, exists := merged.GetSection("core")["pager"]; exists {
+		t.Error("core.pager should no longer be inherited")
+	}
+
+	if err := cfg.UnsetValue(GlobalLayer, "core.editor"); err != nil {
+		t.Fatalf("UnsetValue failed: %v", err)
+	}
+
+	if _, exists := cfg.MergeGlobal().GetSection("core")["editor"]; exists {
+		t.Error("core.editor should be removed from global")
+	}
+}
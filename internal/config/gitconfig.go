@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+)
+
+// GitConfig converts a Profile to a git configuration map.
+// It maps profile fields to git config keys (user.name, user.email, etc.).
+func (p *Profile) GitConfig() map[string]any {
+	gitConfig := make(map[string]any)
+
+	// User section
+	if p.User.Name != "" {
+		gitConfig["user.name"] = p.User.Name
+	}
+
+	if p.User.Email != "" {
+		gitConfig["user.email"] = p.User.Email
+	}
+
+	if p.User.SigningKey != "" {
+		gitConfig["user.signingkey"] = p.User.SigningKey
+	}
+
+	addSectionToConfig(gitConfig, "user", p.User.Extra)
+
+	// URL rewrites, a pattern may rewrite several prefixes
+	for _, url := range p.URL {
+		key := fmt.Sprintf("url \"%s\".insteadOf", url.Pattern)
+
+		switch existing := gitConfig[key].(type) {
+		case nil:
+			gitConfig[key] = url.InsteadOf
+		case []any:
+			gitConfig[key] = append(existing, url.InsteadOf)
+		default:
+			gitConfig[key] = []any{existing, url.InsteadOf}
+		}
+	}
+
+	// Dynamically add all sections from the profile
+	for _, section := range p.SectionNames() {
+		if canonicalSectionName(section) == customSection {
+			continue
+		}
+
+		if sectionMap := p.GetSection(section); sectionMap != nil {
+			addSectionToConfig(gitConfig, sectionPrefix(section), sectionMap)
+		}
+	}
+
+	return gitConfig
+}
+
+// addSectionToConfig adds a section with values to the git configuration map.
+// It delegates to addSectionToConfigRecursive for hierarchical key handling.
+func addSectionToConfig(
+	config map[string]any,
+	section string,
+	values map[string]any,
+) {
+	addSectionToConfigRecursive(config, section, values)
+}
+
+// addSectionToConfigRecursive recursively adds nested configuration values.
+// It handles dot-separated keys by creating nested maps as needed.
+func addSectionToConfigRecursive(
+	config map[string]any,
+	prefix string,
+	values map[string]any,
+) {
+	for k, v := range values {
+		// If the value is a map, it's a subsection - just continue with dot notation
+		// e.g., add.interactive, delta.decorations, delta.interactive
+		if m, ok := v.(map[string]any); ok {
+			key := fmt.Sprintf("%s.%s", prefix, k)
+			addSectionToConfigRecursive(config, key, m)
+		} else {
+			// Leaf value - add it directly
+			key := fmt.Sprintf("%s.%s", prefix, k)
+			config[key] = v
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileGitConfig(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		User: UserConfig{
+			Name:       "Test User",
+			Email:      "test@example.com",
+			SigningKey: "ABCD1234",
+		},
+		URL: []URLConfig{
+			{
+				Pattern:   "ssh://git@github.com/",
+				InsteadOf: "https://github.com/",
+			},
+		},
+		Sections: map[string]any{
+			"core": map[string]any{
+				"editor": "vim",
+			},
+			"push": map[string]any{
+				"default": "simple",
+			},
+		},
+	}
+
+	gitConfig := profile.GitConfig()
+
+	// Verify user config
+	if gitConfig["user.name"] != "Test User" {
+		t.Error("Git config should contain user.name")
+	}
+
+	if gitConfig["user.email"] != "test@example.com" {
+		t.Error("Git config should contain user.email")
+	}
+
+	if gitConfig["user.signingkey"] != "ABCD1234" {
+		t.Error("Git config should contain user.signingkey")
+	}
+
+	// Verify URL rewrite
+	urlKey := `url "ssh://git@github.com/".insteadOf`
+	if gitConfig[urlKey] != "https://github.com/" {
+		t.Error("Git config should contain URL rewrite")
+	}
+
+	// Verify other sections
+	if gitConfig["core.editor"] != "vim" {
+		t.Error("Git config should contain core.editor")
+	}
+
+	if gitConfig["push.default"] != "simple" {
+		t.Error("Git config should contain push.default")
+	}
+}
+
+func TestAddSectionToConfig(t *testing.T) {
+	t.Parallel()
+
+	gitConfig := make(map[string]any)
+
+	section := map[string]any{
+		"editor":   "vim",
+		"autocrlf": "input",
+	}
+
+	addSectionToConfig(gitConfig, "core", section)
+
+	if gitConfig["core.editor"] != "vim" {
+		t.Error("Should add core.editor")
+	}
+
+	if gitConfig["core.autocrlf"] != "input" {
+		t.Error("Should add core.autocrlf")
+	}
+}
+
+func TestAddSectionToConfigNested(t *testing.T) {
+	t.Parallel()
+
+	gitConfig := make(map[string]any)
+
+	section := map[string]any{
+		"interactive": map[string]any{
+			"diffFilter": "delta --color-only",
+		},
+	}
+
+	addSectionToConfig(gitConfig, "add", section)
+
+	if gitConfig["add.interactive.diffFilter"] != "delta --color-only" {
+		t.Error("Should add nested config values")
+	}
+}
+
+func TestProfileGitConfigAllSections(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		User: UserConfig{
+			Name:       "Test User",
+			Email:      "test@example.com",
+			SigningKey: "KEY123",
+		},
+		Sections: map[string]any{
+			"http": map[string]any{
+				"postBuffer": "524288000",
+			},
+			"core": map[string]any{
+				"editor": "vim",
+			},
+			"interactive": map[string]any{
+				"singleKey": "true",
+			},
+			"add": map[string]any{
+				"interactive": map[string]any{
+					"useBuiltin": "false",
+				},
+			},
+			"delta": map[string]any{
+				"navigate": "true",
+			},
+			"push": map[string]any{
+				"default": "current",
+			},
+			"merge": map[string]any{
+				"conflictStyle": "diff3",
+			},
+			"commit": map[string]any{
+				"gpgsign": "true",
+			},
+			"gpg": map[string]any{
+				"program": "gpg2",
+			},
+			"pull": map[string]any{
+				"rebase": "true",
+			},
+			"rerere": map[string]any{
+				"enabled": "true",
+			},
+			"column": map[string]any{
+				"ui": "auto",
+			},
+			"branch": map[string]any{
+				"autoSetupRebase": "always",
+			},
+			"init": map[string]any{
+				"defaultBranch": "main",
+			},
+		},
+	}
+
+	gitConfig := profile.GitConfig()
+
+	// Verify all sections are present
+	sections := []string{
+		"user.name", "user.email", "user.signingkey",
+		"http.postBuffer",
+		"core.editor",
+		"interactive.singleKey",
+		"add.interactive.useBuiltin",
+		"delta.navigate",
+		"push.default",
+		"merge.conflictStyle",
+		"commit.gpgsign",
+		"gpg.program",
+		"pull.rebase",
+		"rerere.enabled",
+		"column.ui",
+		"branch.autoSetupRebase",
+		"init.defaultBranch",
+	}
+
+	for _, key := range sections {
+		if _, exists := gitConfig[key]; !exists {
+			t.Errorf("Git config should contain key: %s", key)
+		}
+	}
+}
+
+func TestProfileGitConfigEmptySections(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		User: UserConfig{
+			Name:  "Test",
+			Email: "test@test.com",
+		},
+		// All other sections empty/nil
+	}
+
+	gitConfig := profile.GitConfig()
+
+	// Should have user config
+	if gitConfig["user.name"] != "Test" {
+		t.Error("Should have user.name")
+	}
+
+	// Other sections should not add keys
+	if val, exists := gitConfig["http.something"]; exists {
+		t.Errorf("Should not have http keys, got: %v", val)
+	}
+}
+
+func TestAddSectionToConfigRecursiveDeepNesting(t *testing.T) {
+	t.Parallel()
+
+	gitConfig := make(map[string]any)
+
+	values := map[string]any{
+		"level1": map[string]any{
+			"level2": map[string]any{
+				"level3": "deepvalue",
+			},
+		},
+	}
+
+	addSectionToConfigRecursive(gitConfig, "section", values)
+
+	if gitConfig["section.level1.level2.level3"] != "deepvalue" {
+		t.Error("Should handle deep nesting")
+	}
+}
+
+func TestProfileGitConfigMultiValued(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		URL: []URLConfig{
+			{Pattern: "ssh://git@github.com/", InsteadOf: "https://github.com/"},
+			{Pattern: "ssh://git@github.com/", InsteadOf: "gh:"},
+			{Pattern: "ssh://git@github.com/", InsteadOf: "github:"},
+		},
+		Sections: map[string]any{
+			"core": map[string]any{
+				"excludesFile": "~/.gitignore",
+			},
+			"http": map[string]any{
+				"extraHeader": []any{"X-One: 1", "X-Two: 2"},
+			},
+		},
+	}
+
+	gitConfig := profile.GitConfig()
+
+	urls, ok := gitConfig[`url "ssh://git@github.com/".insteadOf`].([]any)
+	if !ok || len(urls) != 3 {
+		t.Fatalf("Expected 3 insteadOf values for the same pattern, got %v", urls)
+	}
+
+	headers, ok := gitConfig["http.extraHeader"].([]any)
+	if !ok || len(headers) != 2 {
+		t.Errorf("List values should be kept as lists, got %v", gitConfig["http.extraHeader"])
+	}
+}
+
+func TestProfileGitConfigSubsections(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		User: UserConfig{
+			Name:  "Test",
+			Extra: map[string]any{"useConfigOnly": true},
+		},
+		Sections: map[string]any{
+			`credential "https://github.com"`: map[string]any{
+				"helper": "store",
+			},
+			"filter": map[string]any{
+				"lfs": map[string]any{
+					"required": true,
+				},
+			},
+			"sendemail": map[string]any{
+				"smtpServer": "smtp.example.com",
+			},
+		},
+	}
+
+	gitConfig := profile.GitConfig()
+
+	expected := map[string]any{
+		"credential.https://github.com.helper": "store",
+		"filter.lfs.required":                  true,
+		"sendemail.smtpServer":                 "smtp.example.com",
+		"user.useConfigOnly":                   true,
+	}
+
+	for key, want := range expected {
+		if gitConfig[key] != want {
+			t.Errorf("gitConfig[%q] = %v, want %v", key, gitConfig[key], want)
+		}
+	}
+}
+
+func TestCustomSectionIsNotWritten(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig()
+	cfg.Global["custom"] = map[string]any{"team": "platform"}
+	cfg.Global["core"] = map[string]any{"editor": "vim"}
+	cfg.Profiles["work"] = &Profile{
+		User:     UserConfig{Name: "Test", Email: "test@test.com"},
+		Sections: map[string]any{"custom": map[string]any{"ticket": "OPS"}},
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// The profile keeps its own custom section, global's is not merged in
+	custom := merged.GetSection("custom")
+	if len(custom) != 1 || custom["ticket"] != "OPS" {
+		t.Errorf("Expected only the profile's custom section, got %v", custom)
+	}
+
+	for key := range merged.GitConfig() {
+		if strings.HasPrefix(key, "custom.") {
+			t.Errorf("Custom key %s should not be written to git config", key)
+		}
+	}
+
+	if merged.GitConfig()["core.editor"] != "vim" {
+		t.Error("Global sections should still be merged")
+	}
+}
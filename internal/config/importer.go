@@ -0,0 +1,313 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// reservedSections are profile fields; a git section with the same name
+// would be read back into the field instead.
+var reservedSections = map[string]struct{}{"extends": {}, "directories": {}, "remotes": {}}
+
+// ImportProfile creates a profile named name holding the keys of a git config
+// file. Keys that config.yaml cannot represent, such as include directives,
+// are left out and described in the returned list.
+func (c *Config) ImportProfile(name string, file *git.File) ([]string, error) {
+	if err := c.AddProfile(name, &Profile{}); err != nil {
+		return nil, err
+	}
+
+	return c.importValues(name, file, false), nil
+}
+
+// ImportIncludes migrates a git config split with includeIf directives.
+// Every file included on a gitdir or remote URL condition becomes a profile
+// named after the file, with the conditions turned into directories and
+// remotes rules. The keys of the config at path itself are imported into
+// profile name, or into global when name is empty. Like ImportProfile, it
+// returns what could not be represented.
+func (c *Config) ImportIncludes(name, path string, file *git.File) ([]string, error) {
+	included, skipped := includedProfiles(path, file)
+
+	// Check every name first so that a conflict leaves the config untouched
+	names := make([]string, 0, len(included)+1)
+	if name != "" {
+		names = append(names, name)
+	}
+
+	for _, profile := range included {
+		names = append(names, profile.name)
+	}
+
+	for i, profileName := range names {
+		if _, exists := c.Profiles[profileName]; exists {
+			return nil, errors.WithStack(errors.Newf("profile '%s' already exists", profileName))
+		}
+
+		if slices.Contains(names[:i], profileName) {
+			return nil, errors.WithStack(errors.Newf(
+				"several imported files map to profile '%s', import them one by one with --from", profileName,
+			))
+		}
+	}
+
+	target := GlobalLayer
+	if name != "" {
+		target = name
+		c.Profiles[name] = &Profile{}
+	}
+
+	skipped = append(skipped, c.importValues(target, file, true)...)
+
+	for _, profile := range included {
+		includedFile, err := git.ParseFile(profile.path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+
+			skipped = append(skipped, fmt.Sprintf("%s: included file does not exist", profile.path))
+
+			continue
+		}
+
+		c.Profiles[profile.name] = &Profile{Directories: profile.directories, Remotes: profile.remotes}
+
+		for _, entry := range c.importValues(profile.name, includedFile, false) {
+			skipped = append(skipped, profile.name+": "+entry)
+		}
+	}
+
+	return skipped, nil
+}
+
+// includedProfile is a file included with includeIf, and the rules of the
+// profile created from it.
+type includedProfile struct {
+	name        string
+	path        string
+	directories []string
+	remotes     []string
+}
+
+// includedProfiles groups the includeIf directives of a git config by the file
+// they include, in order of first appearance. Conditions without a profile
+// rule equivalent, such as onbranch:, are described in the returned list.
+func includedProfiles(path string, file *git.File) ([]*includedProfile, []string) {
+	var (
+		included []*includedProfile
+		skipped  []string
+	)
+
+	byPath := make(map[string]*includedProfile)
+
+	for _, include := range file.Includes() {
+		if include.Condition == "" {
+			continue
+		}
+
+		var directory, remote string
+
+		switch {
+		case strings.HasPrefix(include.Condition, gitdirCondition):
+			directory = strings.TrimPrefix(include.Condition, gitdirCondition)
+		case strings.HasPrefix(include.Condition, hasRemoteCondition):
+			remote = strings.TrimPrefix(include.Condition, hasRemoteCondition)
+		default:
+			skipped = append(skipped, fmt.Sprintf("includeIf.%s.path = %s: condition has no profile rule equivalent",
+				include.Condition, include.Path))
+
+			continue
+		}
+
+		resolved := git.ResolveIncludePath(path, include.Path)
+
+		profile, exists := byPath[resolved]
+		if !exists {
+			profile = &includedProfile{name: includeProfileName(resolved), path: resolved}
+			byPath[resolved] = profile
+			included = append(included, profile)
+		}
+
+		if directory != "" {
+			profile.directories = append(profile.directories, directory)
+		}
+
+		if remote != "" {
+			profile.remotes = append(profile.remotes, remote)
+		}
+	}
+
+	return included, skipped
+}
+
+// includeProfileName derives a profile name from an included file:
+// ~/.gitconfig-work, ~/work.gitconfig and ~/work/.gitconfig all give "work".
+func includeProfileName(path string) string {
+	name := strings.TrimPrefix(filepath.Base(path), ".")
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "gitconfig"), ".gitconfig")
+	name = strings.Trim(name, "-_.")
+
+	if name == "" || name == "config" {
+		name = strings.TrimPrefix(filepath.Base(filepath.Dir(path)), ".")
+	}
+
+	return name
+}
+
+// importValues sets the keys of a git config file on global or a profile and
+// returns those it left out. With followed set, the includeIf directives were
+// already turned into profiles and are not reported.
+func (c *Config) importValues(target string, file *git.File, followed bool) []string {
+	var skipped []string
+
+	keys, values := fileValues(file)
+
+	for _, key := range keys {
+		canonical := git.CanonicalKey(key)
+		section, subsection, variable, _ := git.SplitKey(canonical)
+		keyValues := values[canonical]
+
+		switch {
+		case canonical == git.ProfileKey:
+			continue
+		case section == "includeif" && followed:
+			continue
+		case section == "include" || section == "includeif":
+			skipped = append(skipped, fmt.Sprintf("%s = %s: include directives are not imported",
+				key, strings.Join(keyValues, ", ")))
+
+			continue
+		case isReservedSection(section):
+			skipped = append(skipped, fmt.Sprintf("%s: section name is reserved by git-context", key))
+
+			continue
+		case section == "user" && subsection == "" && isTypedUserKey(variable) && len(keyValues) > 1:
+			skipped = append(skipped, fmt.Sprintf("%s: only the last of %d values is imported", key, len(keyValues)))
+			keyValues = keyValues[len(keyValues)-1:]
+		}
+
+		if err := c.SetValue(target, key, keyValues); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	return skipped
+}
+
+// ExtractCommon moves the keys every profile sets to the same value into
+// global, and returns them. URL rewrites and appending lists are left in the
+// profiles, as moving them would change how they combine with global.
+func (c *Config) ExtractCommon() ([]string, error) {
+	names := slices.Sorted(maps.Keys(c.Profiles))
+	if len(names) < 2 {
+		return nil, nil
+	}
+
+	var (
+		common  git.Values
+		spelled map[string]string
+	)
+
+	for _, name := range names {
+		gitConfig := c.Profiles[name].GitConfig()
+		values := git.ConfigValues(gitConfig)
+
+		if common == nil {
+			common = values
+			spelled = make(map[string]string, len(gitConfig))
+
+			for key := range gitConfig {
+				spelled[git.CanonicalKey(key)] = key
+			}
+
+			continue
+		}
+
+		maps.DeleteFunc(common, func(key string, value []string) bool {
+			return !slices.Equal(values[key], value)
+		})
+	}
+
+	var moved []string
+
+	for _, key := range slices.Sorted(maps.Keys(common)) {
+		section, _, _, _ := git.SplitKey(key)
+		if section == "url" || strings.HasSuffix(key, appendSuffix) {
+			continue
+		}
+
+		if err := c.SetValue(GlobalLayer, spelled[key], common[key]); err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			if err := c.RemoveValue(name, spelled[key]); err != nil {
+				return nil, err
+			}
+		}
+
+		moved = append(moved, spelled[key])
+	}
+
+	return moved, nil
+}
+
+// fileValues returns the keys of a git config file as spelled in the file,
+// in order of first appearance, and their values by canonical key.
+// A variable without a value is a boolean true.
+func fileValues(file *git.File) ([]string, map[string][]string) {
+	var keys []string
+
+	values := make(map[string][]string)
+
+	for _, section := range file.Sections {
+		prefix := section.Name
+		if section.HasSubsection {
+			prefix += "." + section.Subsection
+		}
+
+		for _, line := range section.Lines {
+			if line.Key == "" {
+				continue
+			}
+
+			key := prefix + "." + line.Key
+			canonical := git.CanonicalKey(key)
+
+			if _, seen := values[canonical]; !seen {
+				keys = append(keys, key)
+			}
+
+			value := line.Value
+			if line.NoValue {
+				value = "true"
+			}
+
+			values[canonical] = append(values[canonical], value)
+		}
+	}
+
+	return keys, values
+}
+
+// isReservedSection reports whether a git section clashes with a profile field.
+func isReservedSection(section string) bool {
+	_, reserved := reservedSections[section]
+
+	return reserved
+}
+
+// isTypedUserKey reports whether a user.* key is stored in a UserConfig field.
+func isTypedUserKey(name string) bool {
+	_, typed := typedUserKeys[strings.ToLower(name)]
+
+	return typed
+}
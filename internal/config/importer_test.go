@@ -0,0 +1,259 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aanogueira/git-context/internal/git"
+)
+
+func TestImportProfile(t *testing.T) {
+	t.Parallel()
+
+	file, err := git.Parse([]byte(`[user]
+	name = Old Name
+	name = Work User
+	email = work@example.com
+	useConfigOnly
+[core]
+	autocrlf = input
+[credential "https://github.com"]
+	helper =
+	helper = store
+[url "ssh://git@github.com/"]
+	insteadOf = https://github.com/
+[include]
+	path = ~/.gitconfig.local
+[includeIf "gitdir:~/oss/"]
+	path = ~/.gitconfig-oss
+[remotes]
+	default = origin
+[gitcontext]
+	profile = personal
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+
+	skipped, err := cfg.ImportProfile("work", file)
+	if err != nil {
+		t.Fatalf("ImportProfile failed: %v", err)
+	}
+
+	if len(skipped) != 4 {
+		t.Errorf("Expected 4 skipped entries, got %d: %v", len(skipped), skipped)
+	}
+
+	for i, prefix := range []string{"user.name", "include.path", "includeIf.gitdir:~/oss/.path", "remotes.default"} {
+		if i < len(skipped) && !strings.HasPrefix(skipped[i], prefix) {
+			t.Errorf("Expected skipped entry %d to be %s, got %q", i, prefix, skipped[i])
+		}
+	}
+
+	work := cfg.Profiles["work"]
+	if work.User.Name != "Work User" || work.User.Email != "work@example.com" {
+		t.Errorf("Unexpected user: %+v", work.User)
+	}
+
+	if work.User.Extra["useConfigOnly"] != true {
+		t.Errorf("Expected a value-less key to be imported as true, got %v", work.User.Extra)
+	}
+
+	if work.GetSection("core")["autocrlf"] != "input" {
+		t.Errorf("Expected core.autocrlf, got %v", work.GetSection("core"))
+	}
+
+	helpers, _ := work.GetSection(`credential "https://github.com"`)["helper"].([]any)
+	if !slices.Equal(helpers, []any{"", "store"}) {
+		t.Errorf("Expected both credential helpers, got %v", work.Sections)
+	}
+
+	if len(work.URL) != 1 || work.URL[0].InsteadOf != "https://github.com/" {
+		t.Errorf("Unexpected url rewrites: %+v", work.URL)
+	}
+
+	if _, exists := work.Sections["gitcontext"]; exists {
+		t.Error("Expected the profile marker not to be imported")
+	}
+
+	if _, err := cfg.ImportProfile("work", file); err == nil {
+		t.Error("Expected importing over an existing profile to fail")
+	}
+}
+
+func TestExtractCommon(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Profiles: map[string]*Profile{
+			"personal": {
+				User: UserConfig{Name: "Same Name", Email: "me@example.com"},
+				URL:  []URLConfig{{Pattern: "git@github.com:", InsteadOf: "https://github.com/"}},
+				Sections: map[string]any{
+					"core": map[string]any{"autocrlf": "input", "editor": "vim"},
+					"pull": map[string]any{"rebase": true},
+				},
+			},
+			"work": {
+				User: UserConfig{Name: "Same Name", Email: "work@example.com"},
+				URL:  []URLConfig{{Pattern: "git@github.com:", InsteadOf: "https://github.com/"}},
+				Sections: map[string]any{
+					"core": map[string]any{"autoCRLF": "input", "editor": "code"},
+					"pull": map[string]any{"rebase": "true"},
+				},
+			},
+		},
+	}
+
+	moved, err := cfg.ExtractCommon()
+	if err != nil {
+		t.Fatalf("ExtractCommon failed: %v", err)
+	}
+
+	if !slices.Equal(moved, []string{"core.autocrlf", "pull.rebase", "user.name"}) {
+		t.Errorf("Unexpected moved keys: %v", moved)
+	}
+
+	for name, profile := range cfg.Profiles {
+		if profile.User.Name != "" || profile.User.Email == "" {
+			t.Errorf("Expected only user.name to move out of %s, got %+v", name, profile.User)
+		}
+
+		if _, exists := profile.Sections["pull"]; exists || len(profile.GetSection("core")) != 1 {
+			t.Errorf("Unexpected sections left in %s: %v", name, profile.Sections)
+		}
+
+		if len(profile.URL) != 1 {
+			t.Errorf("Expected url rewrites to stay in %s", name)
+		}
+	}
+
+	if cfg.Global["core"].(map[string]any)["autocrlf"] != "input" || cfg.Global["user"].(map[string]any)["name"] != "Same Name" {
+		t.Errorf("Unexpected global: %v", cfg.Global)
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if merged.User.Name != "Same Name" || merged.GetSection("core")["editor"] != "code" {
+		t.Errorf("Expected the merged profile to be unchanged, got %+v", merged)
+	}
+}
+
+func TestImportIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitconfig")
+	clientPath := filepath.Join(dir, "client", ".gitconfig")
+
+	files := map[string]string{
+		path: `[user]
+	name = Your Name
+[includeIf "gitdir:~/work/"]
+	path = .gitconfig-work
+[includeIf "hasconfig:remote.*.url:git@github.com:corp/**"]
+	path = .gitconfig-work
+[includeIf "gitdir:~/client/"]
+	path = ` + clientPath + `
+[includeIf "onbranch:main"]
+	path = .gitconfig-main
+[includeIf "gitdir:~/gone/"]
+	path = .gitconfig-gone
+`,
+		filepath.Join(dir, ".gitconfig-work"): "[user]\n\temail = you@corp.example.com\n[include]\n\tpath = extra\n",
+		clientPath:                            "[user]\n\temail = you@client.example.com\n",
+	}
+
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	file, err := git.ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	cfg := &Config{Profiles: map[string]*Profile{"gone": {}}}
+
+	if _, err := cfg.ImportIncludes("", path, file); err == nil || len(cfg.Profiles) != 1 {
+		t.Fatalf("Expected a name conflict to fail without changes, got %v", err)
+	}
+
+	delete(cfg.Profiles, "gone")
+
+	skipped, err := cfg.ImportIncludes("", path, file)
+	if err != nil {
+		t.Fatalf("ImportIncludes failed: %v", err)
+	}
+
+	if len(skipped) != 3 || !strings.HasPrefix(skipped[0], "includeIf.onbranch:main.path") ||
+		!strings.HasPrefix(skipped[1], "work: include.path") || !strings.Contains(skipped[2], "does not exist") {
+		t.Errorf("Unexpected skipped entries: %v", skipped)
+	}
+
+	if cfg.Global["user"].(map[string]any)["name"] != "Your Name" {
+		t.Errorf("Expected the base file in global, got %v", cfg.Global)
+	}
+
+	work := cfg.Profiles["work"]
+	if work == nil || work.User.Email != "you@corp.example.com" ||
+		!slices.Equal(work.Directories, []string{"~/work/"}) ||
+		!slices.Equal(work.Remotes, []string{"git@github.com:corp/**"}) {
+		t.Errorf("Unexpected work profile: %+v", work)
+	}
+
+	client := cfg.Profiles["client"]
+	if client == nil || client.User.Email != "you@client.example.com" ||
+		!slices.Equal(client.Directories, []string{"~/client/"}) {
+		t.Errorf("Unexpected client profile: %+v", client)
+	}
+
+	if len(cfg.Profiles) != 2 {
+		t.Errorf("Expected 2 profiles, got %v", slices.Sorted(maps.Keys(cfg.Profiles)))
+	}
+
+	// The base file can also become a profile of its own
+	cfg = &Config{Profiles: make(map[string]*Profile)}
+	if _, err := cfg.ImportIncludes("personal", path, file); err != nil {
+		t.Fatalf("ImportIncludes failed: %v", err)
+	}
+
+	if cfg.Profiles["personal"].User.Name != "Your Name" || len(cfg.Global) != 0 {
+		t.Errorf("Expected the base file in the personal profile, got %+v", cfg.Profiles["personal"])
+	}
+}
+
+func TestIncludeProfileName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"/home/me/.gitconfig-work":      "work",
+		"/home/me/.gitconfig_oss":       "oss",
+		"/home/me/.gitconfig.client":    "client",
+		"/home/me/work.gitconfig":       "work",
+		"/home/me/work/.gitconfig":      "work",
+		"/home/me/.config/git/personal": "personal",
+		"/home/me/.config/git/config":   "git",
+	}
+
+	for path, want := range tests {
+		if got := includeProfileName(path); got != want {
+			t.Errorf("includeProfileName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
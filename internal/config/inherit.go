@@ -0,0 +1,193 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// GlobalLayer is the origin reported for values coming from the global section.
+const GlobalLayer = "global"
+
+// Origins maps canonical git keys to the layer that supplied their value.
+type Origins map[string]string
+
+// Of returns the layer that supplied key, or an empty string if it is unknown.
+func (o Origins) Of(key string) string {
+	return o[git.CanonicalKey(key)]
+}
+
+// layer is one level of a merge: global or a single profile.
+// Sections are keyed by canonical section name; a nil value unsets the section.
+type layer struct {
+	name     string
+	urls     []URLConfig
+	sections map[string]any
+}
+
+// Chain returns the profiles that make up profileName, in the order they are
+// applied: the profiles it extends, depth-first and in declaration order, then
+// the profile itself. A profile reached twice is applied only the first time.
+func (c *Config) Chain(profileName string) ([]string, error) {
+	var chain []string
+
+	if err := c.resolveChain(profileName, nil, &chain); err != nil {
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+// resolveChain appends name and its ancestors to chain. Path holds the
+// profiles currently being resolved and is used to report cycles.
+func (c *Config) resolveChain(name string, path []string, chain *[]string) error {
+	if idx := slices.Index(path, name); idx >= 0 {
+		cycle := append(slices.Clone(path[idx:]), name)
+
+		return errors.WithStack(errors.Newf("profile inheritance cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	profile, exists := c.Profiles[name]
+	if !exists || profile == nil {
+		if len(path) > 0 {
+			return errors.WithStack(errors.Newf(
+				"profile '%s' extends unknown profile '%s'", path[len(path)-1], name,
+			))
+		}
+
+		return errors.WithStack(errors.Newf("profile '%s' does not exist", name))
+	}
+
+	path = append(path, name)
+	for _, parent := range profile.Extends {
+		if err := c.resolveChain(parent, path, chain); err != nil {
+			return err
+		}
+	}
+
+	if !slices.Contains(*chain, name) {
+		*chain = append(*chain, name)
+	}
+
+	return nil
+}
+
+// profileLayer converts a profile into a merge layer.
+func profileLayer(name string, profile *Profile) layer {
+	sections := canonicalSections(profile.Sections)
+	delete(sections, customSection)
+
+	// The typed user fields take precedence over a "User" spelled section
+	user := make(map[string]any)
+	if values, ok := sections["user"].(map[string]any); ok {
+		maps.Copy(user, values)
+	}
+
+	maps.Copy(user, userToMap(profile.User))
+	sections["user"] = user
+
+	return layer{
+		name:     name,
+		urls:     profile.URL,
+		sections: sections,
+	}
+}
+
+// mergeLayers merges layers in order, later layers overriding earlier ones.
+// URL rewrites are replaced as a whole by the last layer defining any.
+func mergeLayers(layers []layer) (*Profile, Origins) {
+	sections := make(map[string]map[string]any)
+	origins := make(Origins)
+
+	var urls []URLConfig
+
+	for _, l := range layers {
+		if len(l.urls) > 0 {
+			urls = l.urls
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(l.sections)) {
+			values, ok := l.sections[name].(map[string]any)
+			if !ok {
+				delete(sections, name)
+
+				continue
+			}
+
+			sections[name] = mergeMap(sections[name], values)
+		}
+
+		// Record the last layer setting each key; unset keys are dropped below
+		for _, u := range l.urls {
+			origins[git.CanonicalKey("url."+u.Pattern+".insteadOf")] = l.name
+		}
+
+		for name, value := range l.sections {
+			if values, ok := value.(map[string]any); ok {
+				flattenKeys(sectionPrefix(name), values, func(key string) {
+					origins[key] = l.name
+				})
+			}
+		}
+	}
+
+	merged := &Profile{
+		URL:      urls,
+		Sections: make(map[string]any),
+	}
+
+	final := make(Origins)
+
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		flattenKeys(sectionPrefix(name), sections[name], func(key string) {
+			final[key] = origins[key]
+		})
+
+		if name == "user" {
+			merged.User = userFromMap(sections[name])
+
+			continue
+		}
+
+		merged.SetSection(name, sections[name])
+	}
+
+	for _, u := range urls {
+		key := git.CanonicalKey("url." + u.Pattern + ".insteadOf")
+		final[key] = origins[key]
+	}
+
+	return merged, final
+}
+
+// flattenKeys calls fn with the canonical git key of every value set in
+// values, descending into nested subsections.
+func flattenKeys(prefix string, values map[string]any, fn func(key string)) {
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+
+		name := prefix + "." + strings.TrimSuffix(key, appendSuffix)
+		if nested, ok := value.(map[string]any); ok {
+			flattenKeys(name, nested, fn)
+
+			continue
+		}
+
+		fn(git.CanonicalKey(name))
+	}
+}
+
+// sectionPrefix converts a section name in YAML form, such as
+// `credential "https://github.com"`, into the dotted form used in git keys.
+func sectionPrefix(name string) string {
+	if section, subsection, ok := SplitSectionName(name); ok {
+		return section + "." + subsection
+	}
+
+	return name
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Profiles: map[string]*Profile{
+			"base":        {},
+			"work":        {Extends: []string{"base"}},
+			"signing-ssh": {Extends: []string{"base"}},
+			"client":      {Extends: []string{"work", "signing-ssh"}},
+		},
+	}
+
+	tests := []struct {
+		profile string
+		want    []string
+	}{
+		{"base", []string{"base"}},
+		{"work", []string{"base", "work"}},
+		// base is applied once, before everything that extends it
+		{"client", []string{"base", "work", "signing-ssh", "client"}},
+	}
+
+	for _, tt := range tests {
+		got, err := cfg.Chain(tt.profile)
+		if err != nil {
+			t.Fatalf("Chain(%q) failed: %v", tt.profile, err)
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Chain(%q) = %v, want %v", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Profiles: map[string]*Profile{
+			"a":       {Extends: []string{"b"}},
+			"b":       {Extends: []string{"c"}},
+			"c":       {Extends: []string{"a"}},
+			"self":    {Extends: []string{"self"}},
+			"orphan":  {Extends: []string{"missing"}},
+			"sibling": {Extends: []string{"orphan"}},
+		},
+	}
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"a", "profile inheritance cycle: a -> b -> c -> a"},
+		{"c", "profile inheritance cycle: c -> a -> b -> c"},
+		{"self", "profile inheritance cycle: self -> self"},
+		{"sibling", "profile 'orphan' extends unknown profile 'missing'"},
+		{"missing", "profile 'missing' does not exist"},
+	}
+
+	for _, tt := range tests {
+		_, err := cfg.Chain(tt.profile)
+		if err == nil {
+			t.Errorf("Chain(%q) should fail", tt.profile)
+
+			continue
+		}
+
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Chain(%q) error = %q, want %q", tt.profile, err.Error(), tt.want)
+		}
+	}
+
+	if _, err := cfg.Merge("a"); err == nil {
+		t.Error("Merge should fail for a profile in a cycle")
+	}
+}
+
+func TestMergeExtends(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Global: map[string]any{
+			"core": map[string]any{"editor": "vim"},
+		},
+		Profiles: map[string]*Profile{
+			"base": {
+				Sections: map[string]any{"push": map[string]any{"default": "simple"}},
+			},
+			"work": {
+				Extends: []string{"base"},
+				User:    UserConfig{Name: "Work User", Email: "work@example.com"},
+				URL:     []URLConfig{{Pattern: "ssh://git@github.com/", InsteadOf: "https://github.com/"}},
+			},
+			"signing-ssh": {
+				Extends: []string{"base"},
+				User:    UserConfig{SigningKey: "~/.ssh/id_ed25519.pub"},
+				Sections: map[string]any{
+					"gpg":  map[string]any{"format": "ssh"},
+					"push": map[string]any{"default": "current"},
+				},
+			},
+			"client": {
+				Extends: []string{"work", "signing-ssh"},
+				User:    UserConfig{Email: "me@client.example.com"},
+				Sections: map[string]any{
+					"core": map[string]any{"editor": nil},
+				},
+			},
+		},
+	}
+
+	merged, origins, err := cfg.MergeWithOrigins("client")
+	if err != nil {
+		t.Fatalf("MergeWithOrigins failed: %v", err)
+	}
+
+	if merged.User.Name != "Work User" || merged.User.Email != "me@client.example.com" ||
+		merged.User.SigningKey != "~/.ssh/id_ed25519.pub" {
+		t.Errorf("Unexpected merged user: %+v", merged.User)
+	}
+
+	if len(merged.URL) != 1 {
+		t.Errorf("URL rewrites should be inherited, got %v", merged.URL)
+	}
+
+	// Later parents override earlier ones
+	if merged.GetSection("push")["default"] != "current" {
+		t.Errorf("Expected push.default from signing-ssh, got %v", merged.GetSection("push"))
+	}
+
+	// The profile unsets a key inherited from global
+	if _, exists := merged.GetSection("core")["editor"]; exists {
+		t.Errorf("core.editor should be unset, got %v", merged.GetSection("core"))
+	}
+
+	want := map[string]string{
+		"user.name":                           "work",
+		"user.email":                          "client",
+		"user.signingKey":                     "signing-ssh",
+		"push.default":                        "signing-ssh",
+		"gpg.format":                          "signing-ssh",
+		"url.ssh://git@github.com/.insteadOf": "work",
+	}
+
+	for key, layer := range want {
+		if got := origins.Of(key); got != layer {
+			t.Errorf("Origins.Of(%q) = %q, want %q", key, got, layer)
+		}
+	}
+
+	if got := origins.Of("core.editor"); got != "" {
+		t.Errorf("Unset keys should have no origin, got %q", got)
+	}
+
+	_, origins, err = cfg.MergeWithOrigins("base")
+	if err != nil {
+		t.Fatalf("MergeWithOrigins failed: %v", err)
+	}
+
+	if got := origins.Of("core.editor"); got != GlobalLayer {
+		t.Errorf("Origins.Of(core.editor) = %q, want %q", got, GlobalLayer)
+	}
+}
+
+func TestRemoveExtendedProfile(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Profiles: map[string]*Profile{
+			"base":   {},
+			"client": {Extends: []string{"base"}},
+		},
+	}
+
+	err := cfg.RemoveProfile("base")
+	if err == nil || !strings.Contains(err.Error(), "is extended by") {
+		t.Errorf("Removing an extended profile should fail, got %v", err)
+	}
+
+	if err := cfg.RemoveProfile("client"); err != nil {
+		t.Errorf("RemoveProfile failed: %v", err)
+	}
+}
+
+func TestLoadConfigExtends(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `profiles:
+  work:
+    user:
+      name: Work User
+      email: work@example.com
+  client:
+    extends: [work]
+    user:
+      email: me@client.example.com
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	client := cfg.Profiles["client"]
+	if !slices.Equal(client.Extends, []string{"work"}) {
+		t.Errorf("Extends = %v, want [work]", client.Extends)
+	}
+
+	if len(client.Sections) != 0 {
+		t.Errorf("extends should not be read as a git section, got %v", client.Sections)
+	}
+
+	merged, err := cfg.Merge("client")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if merged.User.Name != "Work User" {
+		t.Errorf("Expected inherited user name, got %q", merged.User.Name)
+	}
+}
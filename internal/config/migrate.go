@@ -0,0 +1,259 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/cockroachdb/errors"
+)
+
+// ConfigVersion is the version of the config.yaml format written by this
+// release. Files without a version key are version 0.
+const ConfigVersion = 1
+
+// migrationStep upgrades config.yaml by one version. migrate records the
+// edits to the document rather than changing a decoded copy, so that keys
+// the Config struct ignores, comments and the layout survive.
+type migrationStep struct {
+	description string
+	migrate     func(d *document, edits *[]edit) error
+}
+
+// migrations holds the step upgrading each version to the next, indexed by
+// the version it upgrades from. A format change appends a step and bumps
+// ConfigVersion.
+var migrations = []migrationStep{
+	0: {
+		description: "record the format version",
+		migrate:     func(*document, *[]edit) error { return nil },
+	},
+}
+
+// Migration is config.yaml upgraded to ConfigVersion.
+type Migration struct {
+	From  int      // Version of the original file
+	Steps []string // Descriptions of the steps applied, in order
+	Data  []byte   // Upgraded content
+
+	original []byte
+}
+
+// Needed reports whether the file was older than ConfigVersion.
+func (m *Migration) Needed() bool {
+	return len(m.Steps) > 0
+}
+
+// BackupFile returns where the original of configFile is kept once migrated.
+func (m *Migration) BackupFile(configFile string) string {
+	return fmt.Sprintf("%s.v%d.bak", configFile, m.From)
+}
+
+// Save writes the upgraded content to configFile, keeping the original in
+// BackupFile.
+func (m *Migration) Save(configFile string) error {
+	if err := fsutil.WriteFile(m.BackupFile(configFile), m.original, 0o644); err != nil {
+		return errors.Wrap(err, "failed to back up config file")
+	}
+
+	if err := fsutil.WriteFile(configFile, m.Data, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write config file")
+	}
+
+	return nil
+}
+
+// Migrate upgrades the content of config.yaml to ConfigVersion step by step.
+// Only the entries the steps change are rewritten, comments and the layout
+// of the rest of the file are kept. Content that is already current, or
+// newer, is returned unchanged.
+func Migrate(data []byte) (*Migration, error) {
+	return migrate(data, migrations)
+}
+
+// migrate upgrades data with steps, to the version following the last one.
+func migrate(data []byte, steps []migrationStep) (*Migration, error) {
+	migration := &Migration{Data: data, original: data}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse config file")
+	}
+
+	if doc.node.Kind == 0 {
+		// An empty file is written in the current format
+		return migration, nil
+	}
+
+	migration.From, err = documentVersion(doc.node.Content[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if migration.From >= len(steps) {
+		return migration, nil
+	}
+
+	if doc.root == nil {
+		// Steps edit the entries of a block mapping in place
+		return nil, errors.WithStack(errRewrite)
+	}
+
+	if mappingValue(doc.root, "version") == nil {
+		// Add the key in place, so that the steps keep it at the top
+		doc, err = doc.withVersionKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for version := migration.From; version < len(steps); version++ {
+		step := steps[version]
+
+		doc, err = doc.edited(step.migrate)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate config from version %d", version)
+		}
+
+		migration.Steps = append(migration.Steps, fmt.Sprintf("%d → %d: %s", version, version+1, step.description))
+	}
+
+	doc, err = doc.edited(func(d *document, edits *[]edit) error {
+		return d.setVersion(edits, len(steps))
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set config version")
+	}
+
+	migration.Data = doc.src
+
+	return migration, nil
+}
+
+// edited returns the document with the edits recorded by record applied.
+func (d *document) edited(record func(d *document, edits *[]edit) error) (*document, error) {
+	var edits []edit
+	if err := record(d, &edits); err != nil {
+		return nil, err
+	}
+
+	data, err := d.apply(edits)
+	if err != nil {
+		return nil, errors.WithSecondaryError(errors.WithStack(errRewrite), err)
+	}
+
+	return parseDocument(data)
+}
+
+// setVersion records the edit setting the version key of the document.
+func (d *document) setVersion(edits *[]edit, version int) error {
+	key, value := mappingEntry(d.root, "version")
+	want := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+
+	if err := d.patchValue(edits, key, value, want); err != nil {
+		return errors.WithSecondaryError(errors.WithStack(errRewrite), err)
+	}
+
+	return nil
+}
+
+// renameKey records the edit renaming the key from of mapping to to. The
+// value, the comments and the position of the entry are kept. A missing key
+// is left alone.
+func (d *document) renameKey(edits *[]edit, mapping *yaml.Node, from, to string) error {
+	key, _ := mappingEntry(mapping, from)
+	if key == nil {
+		return nil
+	}
+
+	if mappingValue(mapping, to) != nil {
+		return errors.WithStack(errors.Newf(
+			"cannot rename '%s' to '%s' at line %d: '%s' is already set", from, to, key.Line, to))
+	}
+
+	start := d.offset(key)
+
+	var (
+		end int
+		err error
+	)
+
+	switch {
+	case key.Style&yaml.DoubleQuotedStyle != 0:
+		end, err = d.quotedEnd(start, '"')
+	case key.Style&yaml.SingleQuotedStyle != 0:
+		end, err = d.quotedEnd(start, '\'')
+	case key.Style == 0 && strings.HasPrefix(string(d.src[start:]), key.Value):
+		end = start + len(key.Value)
+	default:
+		err = errUnpatchable
+	}
+
+	if err != nil {
+		return errors.WithSecondaryError(errors.WithStack(errRewrite), err)
+	}
+
+	text, err := d.render(&yaml.Node{Kind: yaml.ScalarNode, Value: to}, 0)
+	if err != nil {
+		return err
+	}
+
+	*edits = append(*edits, edit{start: start, end: end, text: text})
+
+	return nil
+}
+
+// documentVersion returns the format version of a config.yaml node tree.
+func documentVersion(root *yaml.Node) (int, error) {
+	if root.Kind != yaml.MappingNode {
+		return 0, nil
+	}
+
+	value := mappingValue(root, "version")
+	if value == nil || isNull(value) {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || value.Kind != yaml.ScalarNode || version < 0 {
+		return 0, errors.WithStack(errors.Newf("invalid config version '%s'", value.Value))
+	}
+
+	return version, nil
+}
+
+// withVersionKey returns the document with a version 0 key added after the
+// comment block leading the file, above the first entry.
+func (d *document) withVersionKey() (*document, error) {
+	first := d.root.Content[0]
+	at := d.leadingCommentEnd(first)
+	line := strings.Repeat(" ", first.Column-1) + "version: 0\n"
+
+	data := make([]byte, 0, len(d.src)+len(line))
+	data = append(data, d.src[:at]...)
+	data = append(data, line...)
+	data = append(data, d.src[at:]...)
+
+	return parseDocument(data)
+}
+
+// leadingCommentEnd returns the offset of the first line following the
+// comments, directives and document marker that open the file, up to the line
+// of the first entry.
+func (d *document) leadingCommentEnd(first *yaml.Node) int {
+	line := 0
+
+	for ; line < first.Line-1; line++ {
+		text := strings.TrimSpace(string(d.src[d.lines[line]:d.lines[line+1]]))
+
+		leading := strings.HasPrefix(text, "#") || strings.HasPrefix(text, "%") || text == "---"
+		if !leading {
+			break
+		}
+	}
+
+	return d.lines[line]
+}
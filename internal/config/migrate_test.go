@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unversionedConfig = `# My profiles
+
+# Shared by every profile
+global:
+  core:
+    editor: vim
+
+profiles:
+  work:
+    user:
+      name: Jane
+      email: jane@work.example
+`
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	t.Parallel()
+
+	if len(migrations) != ConfigVersion {
+		t.Errorf("Expected %d migration steps, got %d", ConfigVersion, len(migrations))
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	migration, err := Migrate([]byte(unversionedConfig))
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	if !migration.Needed() || migration.From != 0 || len(migration.Steps) != ConfigVersion {
+		t.Errorf("Unexpected migration from %d with steps %v", migration.From, migration.Steps)
+	}
+
+	want := strings.Replace(unversionedConfig, "# My profiles\n", "# My profiles\nversion: 1\n", 1)
+	if string(migration.Data) != want {
+		t.Errorf("Migrated config:\n%s\nwant:\n%s", migration.Data, want)
+	}
+}
+
+func TestMigrateStepKeepsComments(t *testing.T) {
+	t.Parallel()
+
+	// A later format renaming settings.mode
+	steps := []migrationStep{
+		migrations[0],
+		{
+			description: "rename settings.mode to settings.write",
+			migrate: func(d *document, edits *[]edit) error {
+				settings := mappingValue(d.root, "settings")
+				if settings == nil {
+					return nil
+				}
+
+				return d.renameKey(edits, settings, "mode", "write")
+			},
+		},
+	}
+
+	source := `# My profiles
+version: 1
+settings:
+  # How git-context edits ~/.gitconfig
+  mode: include   # keep my own gitconfig
+  unknown: kept
+
+profiles: {}
+`
+
+	migration, err := migrate([]byte(source), steps)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	want := `# My profiles
+version: 2
+settings:
+  # How git-context edits ~/.gitconfig
+  write: include   # keep my own gitconfig
+  unknown: kept
+
+profiles: {}
+`
+	if string(migration.Data) != want {
+		t.Errorf("Migrated config:\n%s\nwant:\n%s", migration.Data, want)
+	}
+
+	if len(migration.Steps) != 1 || !strings.Contains(migration.Steps[0], "1 → 2") {
+		t.Errorf("Unexpected steps %v", migration.Steps)
+	}
+
+	// The new name must not be set already
+	if _, err := migrate([]byte("version: 1\nsettings:\n  mode: block\n  write: include\n"), steps); err == nil {
+		t.Error("Migrate should fail when the renamed key is already set")
+	}
+}
+
+func TestMigrateVersionKeyPlacement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "no comment",
+			source: "global: {}\nprofiles: {}\n",
+			want:   "version: 1\nglobal: {}\nprofiles: {}\n",
+		},
+		{
+			name:   "comment on the first entry",
+			source: "# My profiles\nglobal: {}\nprofiles: {}\n",
+			want:   "# My profiles\nversion: 1\nglobal: {}\nprofiles: {}\n",
+		},
+		{
+			name:   "document marker",
+			source: "---\n# My profiles\n\n# Shared\nglobal: {}\nprofiles: {}\n",
+			want:   "---\n# My profiles\nversion: 1\n\n# Shared\nglobal: {}\nprofiles: {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			migration, err := Migrate([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("Migrate failed: %v", err)
+			}
+
+			if string(migration.Data) != tt.want {
+				t.Errorf("Migrated config:\n%s\nwant:\n%s", migration.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, source := range []string{"", "version: 1\nprofiles: {}\n", "version: 2\nprofiles: {}\n"} {
+		migration, err := Migrate([]byte(source))
+		if err != nil {
+			t.Fatalf("Migrate failed: %v", err)
+		}
+
+		if migration.Needed() || string(migration.Data) != source {
+			t.Errorf("Expected %q to be left alone, got %q", source, migration.Data)
+		}
+	}
+}
+
+func TestMigrateInvalidVersion(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Migrate([]byte("version: latest\nprofiles: {}\n")); err == nil {
+		t.Error("Migrate should fail for an invalid version")
+	}
+}
+
+func TestLoadConfigMigrates(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(unversionedConfig), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Version != ConfigVersion {
+		t.Errorf("Expected version %d, got %d", ConfigVersion, cfg.Version)
+	}
+
+	// Loading alone leaves the file as it is
+	if data, _ := os.ReadFile(configFile); string(data) != unversionedConfig {
+		t.Errorf("LoadConfig rewrote the config:\n%s", data)
+	}
+
+	cfg.Profiles["work"].User.Email = "jane@corp.example"
+	if err := cfg.SaveConfig(configFile); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	backup, err := os.ReadFile(configFile + ".v0.bak")
+	if err != nil {
+		t.Fatalf("Expected a backup of the original config: %v", err)
+	}
+
+	if string(backup) != unversionedConfig {
+		t.Errorf("Backup differs from the original:\n%s", backup)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	want := strings.Replace(unversionedConfig, "# My profiles\n", "# My profiles\nversion: 1\n", 1)
+	want = strings.Replace(want, "jane@work.example", "jane@corp.example", 1)
+
+	if string(data) != want {
+		t.Errorf("Saved config:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestLoadConfigNewerVersion(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("version: 99\nprofiles: {}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, err := LoadConfig(configFile, "")
+	if err == nil || !strings.Contains(err.Error(), "newer than version") {
+		t.Errorf("Expected a newer version error, got %v", err)
+	}
+}
@@ -9,23 +9,46 @@ import (
 
 // Paths contains all the important path locations for git-context.
 type Paths struct {
-	ConfigDir       string
-	ConfigFile      string
-	GitConfigFile   string
-	GitConfigBackup string
+	ConfigDir         string
+	ConfigFile        string
+	ManagedConfigFile string
+	ProfilesDir       string
+	GitConfigFile     string
+	BackupsDir        string
+	LockFile          string
+	StateFile         string
 }
 
-// NewPaths initializes and creates paths with proper defaults.
-func NewPaths() (*Paths, error) {
-	home, err := os.UserHomeDir()
+// Environment variables overriding the default paths. The --config and
+// --gitconfig flags take precedence over them.
+const (
+	ConfigFileEnv    = "GIT_CONTEXT_CONFIG"
+	GitConfigFileEnv = "GIT_CONTEXT_GITCONFIG"
+)
+
+// NewPaths initializes and creates paths with proper defaults. configFile
+// and gitConfigFile, given by the --config and --gitconfig flags, are used
+// when set; otherwise config.yaml is $GIT_CONTEXT_CONFIG, or
+// git-context/config.yaml below $XDG_CONFIG_HOME (~/.config by default), and
+// the global git config is chosen as git does, see gitConfigFilePath. The
+// files git-context keeps for itself live next to config.yaml.
+func NewPaths(configFile, gitConfigFile string) (*Paths, error) {
+	configFile, err := configFilePath(configFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get user home directory")
+		return nil, err
 	}
 
-	configDir := filepath.Join(home, ".config", "git-context")
-	configFile := filepath.Join(configDir, "config.yaml")
-	gitConfigFile := filepath.Join(home, ".gitconfig")
-	gitConfigBackup := filepath.Join(home, ".gitconfig.bak")
+	gitConfigFile, err = gitConfigFilePath(gitConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	configDir := filepath.Dir(configFile)
+	managedConfigFile := filepath.Join(configDir, "gitconfig")
+	profilesDir := filepath.Join(configDir, "profiles")
+	backupsDir := filepath.Join(configDir, "backups")
+	lockFile := filepath.Join(configDir, "lock")
+	stateFile := filepath.Join(configDir, "state.yaml")
 
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0o755); err != nil {
@@ -33,9 +56,98 @@ func NewPaths() (*Paths, error) {
 	}
 
 	return &Paths{
-		ConfigDir:       configDir,
-		ConfigFile:      configFile,
-		GitConfigFile:   gitConfigFile,
-		GitConfigBackup: gitConfigBackup,
+		ConfigDir:         configDir,
+		ConfigFile:        configFile,
+		ManagedConfigFile: managedConfigFile,
+		ProfilesDir:       profilesDir,
+		GitConfigFile:     gitConfigFile,
+		BackupsDir:        backupsDir,
+		LockFile:          lockFile,
+		StateFile:         stateFile,
 	}, nil
 }
+
+// configFilePath returns the location of config.yaml, path when it is set.
+func configFilePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+
+	if path != "" {
+		return absPath(path)
+	}
+
+	configHome, err := xdgConfigHome()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configHome, "git-context", "config.yaml"), nil
+}
+
+// gitConfigFilePath returns the location of the global git config: path when
+// it is set, then $GIT_CONTEXT_GITCONFIG, then $GIT_CONFIG_GLOBAL, then the
+// file git writes to, ~/.gitconfig unless only $XDG_CONFIG_HOME/git/config
+// exists.
+func gitConfigFilePath(path string) (string, error) {
+	if path != "" {
+		return absPath(path)
+	}
+
+	for _, env := range []string{GitConfigFileEnv, "GIT_CONFIG_GLOBAL"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+
+		if value == os.DevNull {
+			return "", errors.WithStack(errors.Newf("%s is %s, there is no global git config to manage", env, value))
+		}
+
+		return absPath(value)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user home directory")
+	}
+
+	gitConfigFile := filepath.Join(home, ".gitconfig")
+	if _, err := os.Stat(gitConfigFile); err == nil {
+		return gitConfigFile, nil
+	}
+
+	if configHome, err := xdgConfigHome(); err == nil {
+		xdgGitConfigFile := filepath.Join(configHome, "git", "config")
+		if _, err := os.Stat(xdgGitConfigFile); err == nil {
+			return xdgGitConfigFile, nil
+		}
+	}
+
+	return gitConfigFile, nil
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, or ~/.config when it is unset or,
+// as the specification requires ignoring, relative.
+func xdgConfigHome() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user home directory")
+	}
+
+	return filepath.Join(home, ".config"), nil
+}
+
+// absPath makes a path given by the user absolute.
+func absPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve path '%s'", path)
+	}
+
+	return abs, nil
+}
@@ -6,10 +6,25 @@ import (
 	"testing"
 )
 
+// isolateHome points the home directory at a temporary one and clears the
+// environment variables that move the paths.
+func isolateHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	for _, env := range []string{"XDG_CONFIG_HOME", "GIT_CONFIG_GLOBAL", ConfigFileEnv, GitConfigFileEnv} {
+		t.Setenv(env, "")
+	}
+
+	return home
+}
+
 func TestNewPaths(t *testing.T) {
-	t.Parallel()
+	home := isolateHome(t)
 
-	paths, err := NewPaths()
+	paths, err := NewPaths("", "")
 	if err != nil {
 		t.Fatalf("NewPaths failed: %v", err)
 	}
@@ -28,19 +43,22 @@ func TestNewPaths(t *testing.T) {
 		t.Error("ConfigFile should not be empty")
 	}
 
+	// Check that managed git config file path is set
+	if paths.ManagedConfigFile == "" {
+		t.Error("ManagedConfigFile should not be empty")
+	}
+
 	// Check that git config file path is set
 	if paths.GitConfigFile == "" {
 		t.Error("GitConfigFile should not be empty")
 	}
 
-	// Check that backup path is set
-	if paths.GitConfigBackup == "" {
-		t.Error("GitConfigBackup should not be empty")
+	// Check that backups directory is set
+	if paths.BackupsDir == "" {
+		t.Error("BackupsDir should not be empty")
 	}
 
 	// Verify expected paths structure
-	home, _ := os.UserHomeDir()
-
 	expectedConfigDir := filepath.Join(home, ".config", "git-context")
 	if paths.ConfigDir != expectedConfigDir {
 		t.Errorf("Expected ConfigDir %s, got %s", expectedConfigDir, paths.ConfigDir)
@@ -51,22 +69,42 @@ func TestNewPaths(t *testing.T) {
 		t.Errorf("Expected ConfigFile %s, got %s", expectedConfigFile, paths.ConfigFile)
 	}
 
+	expectedManaged := filepath.Join(expectedConfigDir, "gitconfig")
+	if paths.ManagedConfigFile != expectedManaged {
+		t.Errorf("Expected ManagedConfigFile %s, got %s", expectedManaged, paths.ManagedConfigFile)
+	}
+
+	expectedProfilesDir := filepath.Join(expectedConfigDir, "profiles")
+	if paths.ProfilesDir != expectedProfilesDir {
+		t.Errorf("Expected ProfilesDir %s, got %s", expectedProfilesDir, paths.ProfilesDir)
+	}
+
 	expectedGitConfig := filepath.Join(home, ".gitconfig")
 	if paths.GitConfigFile != expectedGitConfig {
 		t.Errorf("Expected GitConfigFile %s, got %s", expectedGitConfig, paths.GitConfigFile)
 	}
 
-	expectedBackup := filepath.Join(home, ".gitconfig.bak")
-	if paths.GitConfigBackup != expectedBackup {
-		t.Errorf("Expected GitConfigBackup %s, got %s", expectedBackup, paths.GitConfigBackup)
+	expectedBackups := filepath.Join(expectedConfigDir, "backups")
+	if paths.BackupsDir != expectedBackups {
+		t.Errorf("Expected BackupsDir %s, got %s", expectedBackups, paths.BackupsDir)
+	}
+
+	expectedLock := filepath.Join(expectedConfigDir, "lock")
+	if paths.LockFile != expectedLock {
+		t.Errorf("Expected LockFile %s, got %s", expectedLock, paths.LockFile)
+	}
+
+	expectedState := filepath.Join(expectedConfigDir, "state.yaml")
+	if paths.StateFile != expectedState {
+		t.Errorf("Expected StateFile %s, got %s", expectedState, paths.StateFile)
 	}
 }
 
 func TestNewPathsCreatesDirectory(t *testing.T) {
-	t.Parallel()
+	isolateHome(t)
 
 	// This test verifies that NewPaths creates the config directory
-	paths, err := NewPaths()
+	paths, err := NewPaths("", "")
 	if err != nil {
 		t.Fatalf("NewPaths failed: %v", err)
 	}
@@ -81,3 +119,118 @@ func TestNewPathsCreatesDirectory(t *testing.T) {
 		t.Error("ConfigDir path exists but is not a directory")
 	}
 }
+
+func TestNewPathsFromEnvironment(t *testing.T) {
+	isolateHome(t)
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "custom", "config.yaml")
+	gitConfigFile := filepath.Join(dir, "gitconfig")
+
+	t.Setenv(ConfigFileEnv, configFile)
+	t.Setenv(GitConfigFileEnv, gitConfigFile)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "ignored"))
+
+	paths, err := NewPaths("", "")
+	if err != nil {
+		t.Fatalf("NewPaths failed: %v", err)
+	}
+
+	if paths.ConfigFile != configFile {
+		t.Errorf("Expected ConfigFile %s, got %s", configFile, paths.ConfigFile)
+	}
+
+	if paths.ConfigDir != filepath.Dir(configFile) {
+		t.Errorf("Expected ConfigDir %s, got %s", filepath.Dir(configFile), paths.ConfigDir)
+	}
+
+	if paths.StateFile != filepath.Join(filepath.Dir(configFile), "state.yaml") {
+		t.Errorf("StateFile %s is not next to the config file", paths.StateFile)
+	}
+
+	if paths.GitConfigFile != gitConfigFile {
+		t.Errorf("Expected GitConfigFile %s, got %s", gitConfigFile, paths.GitConfigFile)
+	}
+}
+
+func TestNewPathsXDGConfigHome(t *testing.T) {
+	home := isolateHome(t)
+
+	configHome := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	paths, err := NewPaths("", "")
+	if err != nil {
+		t.Fatalf("NewPaths failed: %v", err)
+	}
+
+	expectedConfigFile := filepath.Join(configHome, "git-context", "config.yaml")
+	if paths.ConfigFile != expectedConfigFile {
+		t.Errorf("Expected ConfigFile %s, got %s", expectedConfigFile, paths.ConfigFile)
+	}
+
+	// A relative XDG_CONFIG_HOME is ignored
+	t.Setenv("XDG_CONFIG_HOME", "xdg")
+
+	paths, err = NewPaths("", "")
+	if err != nil {
+		t.Fatalf("NewPaths failed: %v", err)
+	}
+
+	expectedConfigFile = filepath.Join(home, ".config", "git-context", "config.yaml")
+	if paths.ConfigFile != expectedConfigFile {
+		t.Errorf("Expected ConfigFile %s, got %s", expectedConfigFile, paths.ConfigFile)
+	}
+}
+
+func TestDefaultGitConfigFile(t *testing.T) {
+	home := isolateHome(t)
+
+	gitConfigFile := filepath.Join(home, ".gitconfig")
+	xdgGitConfigFile := filepath.Join(home, ".config", "git", "config")
+
+	assertGitConfigFile := func(want string) {
+		t.Helper()
+
+		got, err := gitConfigFilePath("")
+		if err != nil {
+			t.Fatalf("gitConfigFilePath failed: %v", err)
+		}
+
+		if got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+
+	// Neither exists: git creates ~/.gitconfig
+	assertGitConfigFile(gitConfigFile)
+
+	// Only the XDG file exists
+	if err := os.MkdirAll(filepath.Dir(xdgGitConfigFile), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if err := os.WriteFile(xdgGitConfigFile, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	assertGitConfigFile(xdgGitConfigFile)
+
+	// Both exist: ~/.gitconfig wins
+	if err := os.WriteFile(gitConfigFile, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	assertGitConfigFile(gitConfigFile)
+
+	// GIT_CONFIG_GLOBAL replaces the lookup
+	global := filepath.Join(home, "global")
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	assertGitConfigFile(global)
+
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	if _, err := gitConfigFilePath(""); err == nil {
+		t.Error("Expected an error for GIT_CONFIG_GLOBAL set to the null device")
+	}
+}
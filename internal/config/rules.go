@@ -0,0 +1,191 @@
+package config
+
+import (
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Include conditions understood by git's includeIf.
+const (
+	gitdirCondition    = "gitdir:"
+	hasRemoteCondition = "hasconfig:remote.*.url:"
+)
+
+// IncludeConditions returns the includeIf conditions selecting the profile:
+// one per directories entry, then one per remotes entry.
+func (p *Profile) IncludeConditions() []string {
+	conditions := make([]string, 0, len(p.Directories)+len(p.Remotes))
+
+	for _, dir := range p.Directories {
+		conditions = append(conditions, gitdirCondition+GitdirPattern(dir))
+	}
+
+	for _, remote := range p.Remotes {
+		conditions = append(conditions, hasRemoteCondition+remote)
+	}
+
+	return conditions
+}
+
+// HasRules reports whether the profile selects repositories by directory or remote.
+func (p *Profile) HasRules() bool {
+	return len(p.Directories) > 0 || len(p.Remotes) > 0
+}
+
+// GitdirPattern converts a directories entry into a gitdir pattern.
+// A plain directory gets a trailing slash, which makes git match every
+// repository below it; glob patterns are used as written.
+func GitdirPattern(dir string) string {
+	if strings.HasSuffix(dir, "/") || strings.ContainsAny(dir, "*?[") {
+		return dir
+	}
+
+	return dir + "/"
+}
+
+// Repository holds what includeIf conditions are evaluated against.
+type Repository struct {
+	GitDir     string
+	RemoteURLs []string
+}
+
+// RuleMatch is a profile rule matching a repository.
+type RuleMatch struct {
+	Profile   string
+	Condition string
+}
+
+// MatchRules returns the rules of every profile matching repo, in the order
+// compile writes them. Git applies included files in that order, so the last
+// match wins. Home and configDir expand "~/" and "./" in gitdir patterns, the
+// latter being the directory of the file holding the includeIf entries.
+func (c *Config) MatchRules(repo Repository, home, configDir string) []RuleMatch {
+	var matches []RuleMatch
+
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		profile := c.Profiles[name]
+		if profile == nil {
+			continue
+		}
+
+		for _, condition := range profile.IncludeConditions() {
+			if matchCondition(condition, repo, home, configDir) {
+				matches = append(matches, RuleMatch{Profile: name, Condition: condition})
+			}
+		}
+	}
+
+	return matches
+}
+
+// matchCondition evaluates an includeIf condition the way git does.
+func matchCondition(condition string, repo Repository, home, configDir string) bool {
+	if pattern, ok := strings.CutPrefix(condition, gitdirCondition); ok {
+		return matchGitdir(pattern, repo.GitDir, home, configDir)
+	}
+
+	if pattern, ok := strings.CutPrefix(condition, hasRemoteCondition); ok {
+		for _, url := range repo.RemoteURLs {
+			if wildmatch(pattern, url) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchGitdir reports whether gitDir matches a gitdir pattern. Like git, the
+// pattern is expanded first and both the path and its resolved form are tried.
+func matchGitdir(pattern, gitDir, home, configDir string) bool {
+	switch {
+	case strings.HasPrefix(pattern, "~/"):
+		pattern = filepath.ToSlash(home) + pattern[1:]
+	case strings.HasPrefix(pattern, "./"):
+		pattern = filepath.ToSlash(configDir) + pattern[1:]
+	case !strings.HasPrefix(pattern, "/") && filepath.VolumeName(pattern) == "":
+		pattern = "**/" + pattern
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	if wildmatch(pattern, filepath.ToSlash(gitDir)) {
+		return true
+	}
+
+	resolved, err := filepath.EvalSymlinks(gitDir)
+
+	return err == nil && wildmatch(pattern, filepath.ToSlash(resolved))
+}
+
+// wildmatch matches text against a glob pattern with git's pathname rules:
+// "*" and "?" stop at slashes, "**/" matches any number of directories and a
+// trailing "/**" matches everything below.
+func wildmatch(pattern, text string) bool {
+	var expr strings.Builder
+
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			atSegmentStart := i == 0 || pattern[i-1] == '/'
+			if i+1 < len(pattern) && pattern[i+1] == '*' && atSegmentStart {
+				switch {
+				case i+2 == len(pattern):
+					expr.WriteString(".*")
+					i++
+
+					continue
+				case pattern[i+2] == '/':
+					expr.WriteString("(?:.*/)?")
+					i += 2
+
+					continue
+				}
+			}
+
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+
+	return err == nil && re.MatchString(text)
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIncludeConditions(t *testing.T) {
+	t.Parallel()
+
+	profile := &Profile{
+		Directories: []string{"~/work", "~/clients/", "~/src/**/corp-*"},
+		Remotes:     []string{"git@github.com:corp/**"},
+	}
+
+	want := []string{
+		"gitdir:~/work/",
+		"gitdir:~/clients/",
+		"gitdir:~/src/**/corp-*",
+		"hasconfig:remote.*.url:git@github.com:corp/**",
+	}
+
+	if got := profile.IncludeConditions(); !slices.Equal(got, want) {
+		t.Errorf("IncludeConditions() = %v, want %v", got, want)
+	}
+
+	if !profile.HasRules() {
+		t.Error("HasRules() should be true")
+	}
+
+	if (&Profile{}).HasRules() {
+		t.Error("HasRules() should be false for a profile without rules")
+	}
+}
+
+func TestWildmatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"/home/me/work/**", "/home/me/work/repo/.git", true},
+		{"/home/me/work/**", "/home/me/personal/repo/.git", false},
+		{"**/work/**", "/home/me/work/repo/.git", true},
+		{"/home/*/work/**", "/home/me/work/repo/.git", true},
+		{"/home/*/work/**", "/home/me/x/work/repo/.git", false},
+		{"/src/**/corp-*/.git", "/src/a/b/corp-api/.git", true},
+		{"/src/**/corp-*/.git", "/src/corp-api/.git", true},
+		{"/src/repo?/.git", "/src/repo1/.git", true},
+		{"/src/repo[0-9]/.git", "/src/repoX/.git", false},
+		{"/src/repo[!0-9]/.git", "/src/repoX/.git", true},
+		{"git@github.com:corp/**", "git@github.com:corp/api.git", true},
+		{"git@github.com:corp/*", "git@github.com:corp/team/api.git", false},
+		{"https://github.com/corp/**", "https://github.com/other/api.git", false},
+		{"https://*.example.com/**", "https://git.example.com/a/b.git", true},
+	}
+
+	for _, tt := range tests {
+		if got := wildmatch(tt.pattern, tt.text); got != tt.want {
+			t.Errorf("wildmatch(%q, %q) = %v, want %v", tt.pattern, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRules(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Profiles: map[string]*Profile{
+			"work":     {Directories: []string{"~/work"}},
+			"client":   {Directories: []string{"~/work/client"}, Remotes: []string{"git@github.com:client/**"}},
+			"relative": {Directories: []string{"./repos/", "sandbox"}},
+			"personal": {Directories: []string{"~/personal"}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		repo Repository
+		want []RuleMatch
+	}{
+		{
+			name: "nested directories, last profile wins",
+			repo: Repository{GitDir: "/home/me/work/client/api/.git"},
+			want: []RuleMatch{
+				{Profile: "client", Condition: "gitdir:~/work/client/"},
+				{Profile: "work", Condition: "gitdir:~/work/"},
+			},
+		},
+		{
+			name: "remote URL",
+			repo: Repository{
+				GitDir:     "/elsewhere/api/.git",
+				RemoteURLs: []string{"https://example.com/x.git", "git@github.com:client/api.git"},
+			},
+			want: []RuleMatch{{Profile: "client", Condition: "hasconfig:remote.*.url:git@github.com:client/**"}},
+		},
+		{
+			name: "relative patterns",
+			repo: Repository{GitDir: "/home/me/.config/repos/a/.git"},
+			want: []RuleMatch{{Profile: "relative", Condition: "gitdir:./repos/"}},
+		},
+		{
+			name: "pattern without leading slash matches anywhere",
+			repo: Repository{GitDir: "/tmp/sandbox/a/.git"},
+			want: []RuleMatch{{Profile: "relative", Condition: "gitdir:sandbox/"}},
+		},
+		{
+			name: "no match",
+			repo: Repository{GitDir: "/srv/other/.git"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := cfg.MatchRules(tt.repo, "/home/me", "/home/me/.config")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MatchRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
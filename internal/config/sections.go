@@ -1,127 +1,203 @@
 package config
 
-// ConfigSections defines all supported git config sections.
-// This makes it easy to add new sections without modifying multiple places.
-var ConfigSections = []string{
-	"add",
-	"alias",
-	"branch",
-	"column",
-	"commit",
-	"core",
-	"delta",
-	"diff",
-	"feature",
-	"fetch",
-	"gpg",
-	"http",
-	"init",
-	"interactive",
-	"maintenance",
-	"merge",
-	"pull",
-	"push",
-	"rebase",
-	"rerere",
-	"tag",
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// typedSections are the sections stored in typed Profile fields rather than
+// in Profile.Sections.
+var typedSections = map[string]struct{}{
+	"url":  {},
+	"user": {},
 }
 
+// customSection holds settings of the user's own. It is kept in config.yaml
+// but neither merged with global nor written to git config.
+const customSection = "custom"
+
 // GetSection returns the section map from a profile by name.
 // This allows dynamic access to profile sections.
 func (p *Profile) GetSection(name string) map[string]any {
-	switch name {
-	case "add":
-		return p.Add
-	case "alias":
-		return p.Alias
-	case "branch":
-		return p.Branch
-	case "column":
-		return p.Column
-	case "commit":
-		return p.Commit
-	case "core":
-		return p.Core
-	case "delta":
-		return p.Delta
-	case "diff":
-		return p.Diff
-	case "feature":
-		return p.Feature
-	case "fetch":
-		return p.Fetch
-	case "gpg":
-		return p.GPG
-	case "http":
-		return p.HTTP
-	case "init":
-		return p.Init
-	case "interactive":
-		return p.Interactive
-	case "maintenance":
-		return p.Maintenance
-	case "merge":
-		return p.Merge
-	case "pull":
-		return p.Pull
-	case "push":
-		return p.Push
-	case "rebase":
-		return p.Rebase
-	case "rerere":
-		return p.Rerere
-	case "tag":
-		return p.Tag
-	default:
-		return nil
+	if values, ok := p.Sections[name].(map[string]any); ok {
+		return values
 	}
+
+	return nil
 }
 
 // SetSection sets the section map in a profile by name.
 // This allows dynamic modification of profile sections.
 func (p *Profile) SetSection(name string, values map[string]any) {
-	switch name {
-	case "add":
-		p.Add = values
-	case "alias":
-		p.Alias = values
-	case "branch":
-		p.Branch = values
-	case "column":
-		p.Column = values
-	case "commit":
-		p.Commit = values
-	case "core":
-		p.Core = values
-	case "delta":
-		p.Delta = values
-	case "diff":
-		p.Diff = values
-	case "feature":
-		p.Feature = values
-	case "fetch":
-		p.Fetch = values
-	case "gpg":
-		p.GPG = values
-	case "http":
-		p.HTTP = values
-	case "init":
-		p.Init = values
-	case "interactive":
-		p.Interactive = values
-	case "maintenance":
-		p.Maintenance = values
-	case "merge":
-		p.Merge = values
-	case "pull":
-		p.Pull = values
-	case "push":
-		p.Push = values
-	case "rebase":
-		p.Rebase = values
-	case "rerere":
-		p.Rerere = values
-	case "tag":
-		p.Tag = values
+	if _, typed := typedSections[name]; typed {
+		return
+	}
+
+	if p.Sections == nil {
+		p.Sections = make(map[string]any)
+	}
+
+	p.Sections[name] = values
+}
+
+// SectionNames returns the sorted names of the untyped sections of a profile.
+func (p *Profile) SectionNames() []string {
+	return slices.Sorted(maps.Keys(p.Sections))
+}
+
+// SplitSectionName splits a section name written as `name "subsection"` into
+// its parts. Names without a quoted subsection are returned unchanged.
+func SplitSectionName(name string) (string, string, bool) {
+	section, rest, found := strings.Cut(name, " ")
+	if !found {
+		return name, "", false
+	}
+
+	rest = strings.TrimSpace(rest)
+	if len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' {
+		return name, "", false
+	}
+
+	subsection := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(rest[1 : len(rest)-1])
+
+	return section, subsection, true
+}
+
+// canonicalSectionName lowercases the section part of a section name.
+// A quoted subsection keeps its case, as git compares it case-sensitively.
+func canonicalSectionName(name string) string {
+	section, _, ok := SplitSectionName(name)
+	if !ok {
+		return strings.ToLower(name)
+	}
+
+	return strings.ToLower(section) + name[len(section):]
+}
+
+// canonicalSections groups sections whose names differ only in case under
+// their canonical name. Values that are neither maps nor null (such as the url
+// list) are left out.
+func canonicalSections(sections map[string]any) map[string]any {
+	result := make(map[string]any)
+
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		key := canonicalSectionName(name)
+
+		switch values := sections[name].(type) {
+		case nil:
+			result[key] = nil
+		case map[string]any:
+			if base, ok := result[key].(map[string]any); ok {
+				combined := maps.Clone(base)
+				maps.Copy(combined, values)
+				result[key] = combined
+			} else {
+				result[key] = values
+			}
+		}
+	}
+
+	return result
+}
+
+// findCollisions reports section and key names in sections that differ only
+// in case.
+func findCollisions(scope string, sections map[string]any) []string {
+	var warnings []string
+
+	seen := make(map[string]string)
+
+	for _, name := range slices.Sorted(maps.Keys(sections)) {
+		key := canonicalSectionName(name)
+		if other, exists := seen[key]; exists {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s: sections '%s' and '%s' differ only in case", scope, other, name,
+			))
+		} else {
+			seen[key] = name
+		}
+
+		if values, ok := sections[name].(map[string]any); ok {
+			warnings = append(warnings, findKeyCollisions(scope, name, values)...)
+		}
+	}
+
+	return warnings
+}
+
+// findKeyCollisions reports variable names of a section, or of its nested
+// subsections, that differ only in case.
+func findKeyCollisions(scope, prefix string, values map[string]any) []string {
+	var warnings []string
+
+	seen := make(map[string]string)
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if nested, ok := values[key].(map[string]any); ok {
+			warnings = append(warnings, findKeyCollisions(scope, prefix+"."+key, nested)...)
+
+			continue
+		}
+
+		lower := strings.ToLower(key)
+		if other, exists := seen[lower]; exists {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s: keys '%s.%s' and '%s.%s' differ only in case", scope, prefix, other, prefix, key,
+			))
+		} else {
+			seen[lower] = key
+		}
+	}
+
+	return warnings
+}
+
+// userToMap converts the user section into a map of git keys.
+func userToMap(user UserConfig) map[string]any {
+	values := make(map[string]any)
+	maps.Copy(values, user.Extra)
+
+	if user.Name != "" {
+		values["name"] = user.Name
+	}
+
+	if user.Email != "" {
+		values["email"] = user.Email
+	}
+
+	if user.SigningKey != "" {
+		values["signingkey"] = user.SigningKey
+	}
+
+	return values
+}
+
+// userFromMap converts a map of git keys into the typed user section. Keys
+// are matched case-insensitively, as git does.
+func userFromMap(values map[string]any) UserConfig {
+	var user UserConfig
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		value := values[key]
+		str, isString := value.(string)
+
+		switch lower := strings.ToLower(key); {
+		case lower == "name" && isString:
+			user.Name = str
+		case lower == "email" && isString:
+			user.Email = str
+		case lower == "signingkey" && isString:
+			user.SigningKey = str
+		default:
+			if user.Extra == nil {
+				user.Extra = make(map[string]any)
+			}
+
+			user.Extra[key] = value
+		}
 	}
+
+	return user
 }
@@ -1,81 +1,44 @@
 package config
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
 	"slices"
 	"testing"
 )
 
-//nolint:gocognit
 func TestGetSection(t *testing.T) {
 	t.Parallel()
 
 	// Factory function to create a fresh profile for each test
 	newTestProfile := func() *Profile {
 		return &Profile{
-			HTTP: map[string]any{
-				"postBuffer": "524288000",
-			},
-			Core: map[string]any{
-				"editor": "vim",
-			},
-			Interactive: map[string]any{
-				"diffFilter": "delta --color-only",
-			},
-			Add: map[string]any{
-				"interactive": map[string]any{
-					"useBuiltin": true,
+			Sections: map[string]any{
+				"http": map[string]any{
+					"postBuffer": "524288000",
 				},
-			},
-			Delta: map[string]any{
-				"navigate": true,
-			},
-			Push: map[string]any{
-				"default": "current",
-			},
-			Merge: map[string]any{
-				"conflictStyle": "diff3",
-			},
-			Commit: map[string]any{
-				"gpgSign": true,
-			},
-			GPG: map[string]any{
-				"format": "ssh",
-			},
-			Pull: map[string]any{
-				"rebase": true,
-			},
-			Rerere: map[string]any{
-				"enabled": true,
-			},
-			Column: map[string]any{
-				"ui": "auto",
-			},
-			Branch: map[string]any{
-				"sort": "-committerdate",
-			},
-			Init: map[string]any{
-				"defaultBranch": "main",
-			},
-			Diff: map[string]any{
-				"algorithm": "histogram",
-			},
-			Fetch: map[string]any{
-				"prune": true,
-			},
-			Rebase: map[string]any{
-				"autoStash": true,
-			},
-			Maintenance: map[string]any{
-				"auto": false,
-			},
-			Feature: map[string]any{
-				"manyFiles": true,
-			},
-			Alias: map[string]any{
-				"st": "status",
-			},
-			Tag: map[string]any{
-				"sort": "-version:refname",
+				"core": map[string]any{
+					"editor": "vim",
+				},
+				"add": map[string]any{
+					"interactive": map[string]any{
+						"useBuiltin": true,
+					},
+				},
+				"alias": map[string]any{
+					"st": "status",
+				},
+				"sendemail": map[string]any{
+					"smtpServer": "smtp.example.com",
+				},
+				`credential "https://github.com"`: map[string]any{
+					"helper": "store",
+				},
+				"safe": map[string]any{
+					"directory": []any{"/srv/a", "/srv/b"},
+				},
+				"invalid": "not a map",
 			},
 		}
 	}
@@ -87,25 +50,12 @@ func TestGetSection(t *testing.T) {
 	}{
 		{"HTTP section", "http", map[string]any{"postBuffer": "524288000"}},
 		{"Core section", "core", map[string]any{"editor": "vim"}},
-		{"Interactive section", "interactive", map[string]any{"diffFilter": "delta --color-only"}},
 		{"Add section", "add", map[string]any{"interactive": map[string]any{"useBuiltin": true}}},
-		{"Delta section", "delta", map[string]any{"navigate": true}},
-		{"Push section", "push", map[string]any{"default": "current"}},
-		{"Merge section", "merge", map[string]any{"conflictStyle": "diff3"}},
-		{"Commit section", "commit", map[string]any{"gpgSign": true}},
-		{"GPG section", "gpg", map[string]any{"format": "ssh"}},
-		{"Pull section", "pull", map[string]any{"rebase": true}},
-		{"Rerere section", "rerere", map[string]any{"enabled": true}},
-		{"Column section", "column", map[string]any{"ui": "auto"}},
-		{"Branch section", "branch", map[string]any{"sort": "-committerdate"}},
-		{"Init section", "init", map[string]any{"defaultBranch": "main"}},
-		{"Diff section", "diff", map[string]any{"algorithm": "histogram"}},
-		{"Fetch section", "fetch", map[string]any{"prune": true}},
-		{"Rebase section", "rebase", map[string]any{"autoStash": true}},
-		{"Maintenance section", "maintenance", map[string]any{"auto": false}},
-		{"Feature section", "feature", map[string]any{"manyFiles": true}},
 		{"Alias section", "alias", map[string]any{"st": "status"}},
-		{"Tag section", "tag", map[string]any{"sort": "-version:refname"}},
+		{"Sendemail section", "sendemail", map[string]any{"smtpServer": "smtp.example.com"}},
+		{"Credential subsection", `credential "https://github.com"`, map[string]any{"helper": "store"}},
+		{"Safe section", "safe", map[string]any{"directory": []any{"/srv/a", "/srv/b"}}},
+		{"Non-map section", "invalid", nil},
 		{"Unknown section", "unknown", nil},
 		{"Empty section", "", nil},
 	}
@@ -125,17 +75,8 @@ func TestGetSection(t *testing.T) {
 				return
 			}
 
-			if result == nil {
-				t.Errorf("GetSection(%q) = nil, want %v", tt.section, tt.expected)
-
-				return
-			}
-
-			// Deep compare maps
 			if len(result) != len(tt.expected) {
-				t.Errorf("GetSection(%q) length = %d, want %d", tt.section, len(result), len(tt.expected))
-
-				return
+				t.Fatalf("GetSection(%q) = %v, want %v", tt.section, result, tt.expected)
 			}
 
 			for key, expectedValue := range tt.expected {
@@ -146,22 +87,21 @@ func TestGetSection(t *testing.T) {
 					continue
 				}
 
-				// Handle nested maps
-				if expectedMap, ok := expectedValue.(map[string]any); ok {
+				switch expected := expectedValue.(type) {
+				case map[string]any:
 					actualMap, ok := actualValue.(map[string]any)
-					if !ok {
-						t.Errorf("GetSection(%q)[%q] type mismatch, got %T, want map[string]any", tt.section, key, actualValue)
-
-						continue
+					if !ok || !maps.Equal(actualMap, expected) {
+						t.Errorf("GetSection(%q)[%q] = %v, want %v", tt.section, key, actualValue, expected)
 					}
-
-					for k, v := range expectedMap {
-						if actualMap[k] != v {
-							t.Errorf("GetSection(%q)[%q][%q] = %v, want %v", tt.section, key, k, actualMap[k], v)
-						}
+				case []any:
+					actualList, ok := actualValue.([]any)
+					if !ok || !slices.Equal(actualList, expected) {
+						t.Errorf("GetSection(%q)[%q] = %v, want %v", tt.section, key, actualValue, expected)
+					}
+				default:
+					if actualValue != expectedValue {
+						t.Errorf("GetSection(%q)[%q] = %v, want %v", tt.section, key, actualValue, expectedValue)
 					}
-				} else if actualValue != expectedValue {
-					t.Errorf("GetSection(%q)[%q] = %v, want %v", tt.section, key, actualValue, expectedValue)
 				}
 			}
 		})
@@ -176,28 +116,12 @@ func TestSetSection(t *testing.T) {
 		section string
 		values  map[string]any
 	}{
-		{"Set HTTP", "http", map[string]any{"postBuffer": "524288000"}},
 		{"Set Core", "core", map[string]any{"editor": "nvim"}},
-		{"Set Interactive", "interactive", map[string]any{"diffFilter": "delta"}},
-		{"Set Add", "add", map[string]any{"interactive.useBuiltin": true}},
-		{"Set Delta", "delta", map[string]any{"navigate": true}},
-		{"Set Push", "push", map[string]any{"default": "simple"}},
 		{"Set Merge", "merge", map[string]any{"conflictStyle": "zdiff3"}},
-		{"Set Commit", "commit", map[string]any{"gpgSign": false}},
-		{"Set GPG", "gpg", map[string]any{"format": "openpgp"}},
-		{"Set Pull", "pull", map[string]any{"ff": "only"}},
-		{"Set Rerere", "rerere", map[string]any{"enabled": false}},
-		{"Set Column", "column", map[string]any{"ui": "never"}},
-		{"Set Branch", "branch", map[string]any{"autoSetupRebase": "always"}},
-		{"Set Init", "init", map[string]any{"defaultBranch": "develop"}},
-		{"Set Diff", "diff", map[string]any{"algorithm": "patience"}},
-		{"Set Fetch", "fetch", map[string]any{"prune": false}},
-		{"Set Rebase", "rebase", map[string]any{"autoSquash": true}},
-		{"Set Maintenance", "maintenance", map[string]any{"auto": true}},
-		{"Set Feature", "feature", map[string]any{"experimental": true}},
-		{"Set Alias", "alias", map[string]any{"co": "checkout"}},
-		{"Set Tag", "tag", map[string]any{"gpgSign": true}},
-		{"Set unknown section", "unknown", map[string]any{"key": "value"}},
+		{"Set Sendemail", "sendemail", map[string]any{"smtpServer": "smtp.example.com"}},
+		{"Set Safe", "safe", map[string]any{"directory": "*"}},
+		{"Set LFS filter", `filter "lfs"`, map[string]any{"required": true}},
+		{"Set HTTP subsection", `http "https://corp.example.com"`, map[string]any{"sslVerify": false}},
 	}
 
 	for _, tt := range tests {
@@ -208,131 +132,227 @@ func TestSetSection(t *testing.T) {
 			profile.SetSection(tt.section, tt.values)
 
 			result := profile.GetSection(tt.section)
-
-			// For unknown sections, result should be nil
-			if tt.section == "unknown" {
-				if result != nil {
-					t.Errorf("SetSection(%q) should not set unknown section, got %v", tt.section, result)
-				}
-
-				return
+			if !maps.Equal(result, tt.values) {
+				t.Errorf("SetSection(%q) then GetSection = %v, want %v", tt.section, result, tt.values)
 			}
 
-			if result == nil {
-				t.Errorf("SetSection(%q) did not set section", tt.section)
-
-				return
+			if !slices.Equal(profile.SectionNames(), []string{tt.section}) {
+				t.Errorf("SectionNames() = %v, want [%s]", profile.SectionNames(), tt.section)
 			}
+		})
+	}
+}
 
-			if len(result) != len(tt.values) {
-				t.Errorf("SetSection(%q) length = %d, want %d", tt.section, len(result), len(tt.values))
+func TestSetSectionIgnoresTypedSections(t *testing.T) {
+	t.Parallel()
 
-				return
-			}
+	profile := &Profile{}
+	profile.SetSection("user", map[string]any{"name": "Test"})
+	profile.SetSection("url", map[string]any{"pattern": "x"})
 
-			for key, expectedValue := range tt.values {
-				actualValue, ok := result[key]
-				if !ok {
-					t.Errorf("SetSection(%q) missing key %q", tt.section, key)
+	if len(profile.Sections) != 0 {
+		t.Errorf("Typed sections should not be stored in Sections, got %v", profile.Sections)
+	}
+}
 
-					continue
-				}
+func TestSectionNames(t *testing.T) {
+	t.Parallel()
 
-				if actualValue != expectedValue {
-					t.Errorf("SetSection(%q)[%q] = %v, want %v", tt.section, key, actualValue, expectedValue)
-				}
-			}
-		})
+	profile := &Profile{
+		Sections: map[string]any{
+			"push":                "",
+			"core":                "",
+			`remote "origin"`:     "",
+			`branch "main"`:       "",
+			"maintenance":         "",
+			`includeIf "gitdir:"`: "",
+		},
+	}
+
+	want := []string{`branch "main"`, "core", `includeIf "gitdir:"`, "maintenance", "push", `remote "origin"`}
+	if got := profile.SectionNames(); !slices.Equal(got, want) {
+		t.Errorf("SectionNames() = %v, want %v", got, want)
 	}
 }
 
-func TestConfigSections(t *testing.T) {
+func TestSplitSectionName(t *testing.T) {
 	t.Parallel()
 
-	// Verify ConfigSections contains expected sections
-	expectedSections := map[string]bool{
-		"http":        true,
-		"core":        true,
-		"interactive": true,
-		"add":         true,
-		"delta":       true,
-		"push":        true,
-		"merge":       true,
-		"commit":      true,
-		"gpg":         true,
-		"pull":        true,
-		"rerere":      true,
-		"column":      true,
-		"branch":      true,
-		"init":        true,
-		"diff":        true,
-		"fetch":       true,
-		"rebase":      true,
-		"maintenance": true,
-		"feature":     true,
-		"alias":       true,
-		"tag":         true,
-	}
-
-	if len(ConfigSections) != len(expectedSections) {
-		t.Errorf("ConfigSections length = %d, want %d", len(ConfigSections), len(expectedSections))
-	}
-
-	for _, section := range ConfigSections {
-		if !expectedSections[section] {
-			t.Errorf("ConfigSections contains unexpected section: %q", section)
+	tests := []struct {
+		input      string
+		section    string
+		subsection string
+		ok         bool
+	}{
+		{"core", "core", "", false},
+		{`credential "https://github.com"`, "credential", "https://github.com", true},
+		{`filter "lfs"`, "filter", "lfs", true},
+		{`remote "with \"quotes\""`, "remote", `with "quotes"`, true},
+		{`http "https://corp/a b"`, "http", "https://corp/a b", true},
+		{"broken subsection", "broken subsection", "", false},
+	}
+
+	for _, tt := range tests {
+		section, subsection, ok := SplitSectionName(tt.input)
+		if section != tt.section || subsection != tt.subsection || ok != tt.ok {
+			t.Errorf("SplitSectionName(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.input, section, subsection, ok, tt.section, tt.subsection, tt.ok)
 		}
 	}
+}
 
-	for section := range expectedSections {
-		found := false
+func TestUserMapRoundTrip(t *testing.T) {
+	t.Parallel()
 
-		if slices.Contains(ConfigSections, section) {
-			found = true
-		}
+	user := UserConfig{
+		Name:       "Test User",
+		Email:      "test@example.com",
+		SigningKey: "KEY",
+		Extra:      map[string]any{"useConfigOnly": true},
+	}
 
-		if !found {
-			t.Errorf("ConfigSections missing expected section: %q", section)
-		}
+	values := userToMap(user)
+	if len(values) != 4 {
+		t.Errorf("Expected 4 user keys, got %v", values)
+	}
+
+	result := userFromMap(values)
+	if result.Name != user.Name || result.Email != user.Email || result.SigningKey != user.SigningKey {
+		t.Errorf("userFromMap(userToMap(u)) = %+v, want %+v", result, user)
+	}
+
+	if result.Extra["useConfigOnly"] != true {
+		t.Errorf("Extra user keys should be preserved, got %v", result.Extra)
 	}
 }
 
-func TestGetSetSectionRoundTrip(t *testing.T) {
+func TestUserFromMapKeyCase(t *testing.T) {
 	t.Parallel()
 
-	testValues := map[string]any{
-		"key1": "value1",
-		"key2": true,
-		"key3": 123,
+	user := userFromMap(map[string]any{
+		"Name":       "Test User",
+		"EMAIL":      "test@example.com",
+		"signingKey": "KEY",
+	})
+
+	if user.Name != "Test User" || user.Email != "test@example.com" || user.SigningKey != "KEY" {
+		t.Errorf("Expected the typed fields to be set, got %+v", user)
 	}
 
-	for _, section := range ConfigSections {
-		t.Run(section, func(t *testing.T) {
-			t.Parallel()
+	if len(user.Extra) != 0 {
+		t.Errorf("Expected no extra user keys, got %v", user.Extra)
+	}
+}
 
-			profile := &Profile{}
+func TestMergeSigningKeyCase(t *testing.T) {
+	t.Parallel()
 
-			// Set the section
-			profile.SetSection(section, testValues) // Get the section back
-			result := profile.GetSection(section)
+	// signingKey is spelled as git documents it
+	cfg := &Config{
+		Global: map[string]any{
+			"user": map[string]any{"signingkey": "GLOBAL"},
+		},
+		Profiles: map[string]*Profile{
+			"work": {User: UserConfig{Email: "work@example.com", Extra: map[string]any{"signingKey": "WORK"}}},
+		},
+	}
 
-			if result == nil {
-				t.Fatalf("GetSection(%q) returned nil after SetSection", section)
-			}
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
 
-			// Verify values match
-			for key, expectedValue := range testValues {
-				actualValue, ok := result[key]
-				if !ok {
-					t.Errorf("Missing key %q in section %q", key, section)
+	if merged.User.SigningKey != "WORK" || len(merged.User.Extra) != 0 {
+		t.Errorf("Expected the profile signing key in the typed field, got %+v", merged.User)
+	}
 
-					continue
-				}
+	gitConfig := merged.GitConfig()
+	if gitConfig["user.signingkey"] != "WORK" || len(gitConfig) != 2 {
+		t.Errorf("Expected a single signing key, got %v", gitConfig)
+	}
+}
 
-				if actualValue != expectedValue {
-					t.Errorf("Section %q key %q = %v, want %v", section, key, actualValue, expectedValue)
-				}
-			}
-		})
+func TestLoadConfigArbitrarySections(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `global:
+  core:
+    editor: vim
+  filter "lfs":
+    clean: git-lfs clean -- %f
+    required: true
+profiles:
+  work:
+    user:
+      name: Work User
+      email: work@example.com
+      useConfigOnly: true
+    url:
+      - pattern: ssh://git@github.com/
+        insteadOf: https://github.com/
+    credential "https://github.com":
+      helper: store
+    http:
+      https://corp.example.com:
+        sslVerify: false
+    sendemail:
+      smtpServer: smtp.example.com
+    delta:
+      navigate: true
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	profile, err := cfg.GetProfile("work")
+	if err != nil {
+		t.Fatalf("GetProfile failed: %v", err)
+	}
+
+	if profile.User.Email != "work@example.com" || profile.User.Extra["useConfigOnly"] != true {
+		t.Errorf("Unexpected user section: %+v", profile.User)
+	}
+
+	if len(profile.URL) != 1 {
+		t.Errorf("Expected 1 URL rewrite, got %d", len(profile.URL))
+	}
+
+	want := []string{`credential "https://github.com"`, "delta", "http", "sendemail"}
+	if got := profile.SectionNames(); !slices.Equal(got, want) {
+		t.Errorf("SectionNames() = %v, want %v", got, want)
+	}
+
+	merged, err := cfg.Merge("work")
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if merged.GetSection(`filter "lfs"`)["required"] != true {
+		t.Error("Global subsections should be merged into the profile")
+	}
+
+	if merged.GetSection("core")["editor"] != "vim" {
+		t.Error("Global sections should be merged into the profile")
+	}
+
+	// Saving keeps the sections at the profile level
+	if err := cfg.SaveConfig(configFile); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	reloaded, err := LoadConfig(configFile, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if reloaded.Profiles["work"].GetSection(`credential "https://github.com"`)["helper"] != "store" {
+		t.Error("Subsections should survive a save and reload")
 	}
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/cockroachdb/errors"
+)
+
+// State is the runtime state of git-context. It is kept apart from
+// config.yaml, which switching profiles never writes.
+type State struct {
+	Current    string    `yaml:"current,omitempty"`    // Profile last switched to
+	Previous   string    `yaml:"previous,omitempty"`   // Profile active before it
+	SwitchedAt time.Time `yaml:"switchedAt,omitempty"` // Time of the last switch
+}
+
+// LoadState reads the state file, returning an empty state when it does not
+// exist.
+func LoadState(stateFile string) (*State, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to read state file")
+	}
+
+	state := &State{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, "failed to parse state file")
+	}
+
+	return state, nil
+}
+
+// SaveState writes the state file.
+func (s *State) SaveState(stateFile string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal state")
+	}
+
+	if err := fsutil.WriteFile(stateFile, data, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write state file")
+	}
+
+	return nil
+}
+
+// RecordSwitch records a switch from active to profile at the given time.
+// An empty active falls back to the profile last switched to, and switching
+// again to the same profile keeps the previous one.
+func (s *State) RecordSwitch(active, profile string, at time.Time) {
+	if active == "" {
+		active = s.Current
+	}
+
+	if active != profile {
+		s.Previous = active
+	}
+
+	s.Current = profile
+	s.SwitchedAt = at
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadStateMissing(t *testing.T) {
+	t.Parallel()
+
+	state, err := LoadState(filepath.Join(t.TempDir(), "state.yaml"))
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+
+	if state.Current != "" || state.Previous != "" || !state.SwitchedAt.IsZero() {
+		t.Errorf("Expected empty state, got %+v", state)
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	stateFile := filepath.Join(t.TempDir(), "state.yaml")
+	switched := time.Date(2025, 1, 14, 9, 30, 12, 0, time.UTC)
+
+	state := &State{}
+	state.RecordSwitch("", "work", switched)
+	state.RecordSwitch("", "personal", switched)
+
+	if err := state.SaveState(stateFile); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	loaded, err := LoadState(stateFile)
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+
+	if loaded.Current != "personal" || loaded.Previous != "work" {
+		t.Errorf("Expected personal after work, got %+v", loaded)
+	}
+
+	if !loaded.SwitchedAt.Equal(switched) {
+		t.Errorf("Expected switch time %v, got %v", switched, loaded.SwitchedAt)
+	}
+}
+
+func TestRecordSwitch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		state        State
+		active       string
+		profile      string
+		wantPrevious string
+	}{
+		{"first switch", State{}, "", "work", ""},
+		{"from recorded profile", State{Current: "work"}, "", "personal", "work"},
+		{"from live profile", State{Current: "work"}, "school", "personal", "school"},
+		{"same profile keeps previous", State{Current: "work", Previous: "personal"}, "work", "work", "personal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			state := tt.state
+			state.RecordSwitch(tt.active, tt.profile, now)
+
+			if state.Current != tt.profile {
+				t.Errorf("Expected current '%s', got '%s'", tt.profile, state.Current)
+			}
+
+			if state.Previous != tt.wantPrevious {
+				t.Errorf("Expected previous '%s', got '%s'", tt.wantPrevious, state.Previous)
+			}
+		})
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	t.Parallel()
+
+	stateFile := filepath.Join(t.TempDir(), "state.yaml")
+	if err := os.WriteFile(stateFile, []byte("current: [\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	if _, err := LoadState(stateFile); err == nil {
+		t.Error("LoadState should fail for invalid YAML")
+	}
+}
@@ -0,0 +1,586 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aanogueira/git-context/internal/git"
+	"github.com/cockroachdb/errors"
+)
+
+// Severity tells whether a diagnostic prevents loading the configuration.
+type Severity string
+
+// Diagnostic severities.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in config.yaml. Line and Column are 1-based,
+// Column is 0 when only the line is known.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+// Format returns the diagnostic prefixed with its location in file.
+func (d Diagnostic) Format(file string) string {
+	if d.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", file, d.Line, d.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", file, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics are the problems found in config.yaml, in file order.
+type Diagnostics []Diagnostic
+
+// Errors returns the diagnostics that prevent loading the configuration.
+func (d Diagnostics) Errors() Diagnostics {
+	return d.bySeverity(SeverityError)
+}
+
+// Warnings returns the diagnostics that do not prevent loading.
+func (d Diagnostics) Warnings() Diagnostics {
+	return d.bySeverity(SeverityWarning)
+}
+
+func (d Diagnostics) bySeverity(severity Severity) Diagnostics {
+	var result Diagnostics
+
+	for _, diagnostic := range d {
+		if diagnostic.Severity == severity {
+			result = append(result, diagnostic)
+		}
+	}
+
+	return result
+}
+
+// ValidationError reports the errors found in a configuration file.
+type ValidationError struct {
+	File        string
+	Diagnostics Diagnostics
+}
+
+// Error lists the diagnostics, one per line.
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, diagnostic := range e.Diagnostics {
+		lines[i] = diagnostic.Format(e.File)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Keys of config.yaml with a fixed meaning.
+var (
+	rootKeys     = []string{"version", "settings", "global", "profiles"}
+	settingsKeys = []string{"mode", "backupRetention", "symlinks"}
+	profileKeys  = []string{"extends", "directories", "remotes", "url", "user"}
+	userKeys     = []string{"name", "email", "signingkey", "useConfigOnly"}
+	urlKeys      = []string{"pattern", "insteadOf"}
+)
+
+// gitSections are common git sections, which are not mistaken for typos of
+// profile keys.
+var gitSections = []string{
+	"advice", "alias", "am", "apply", "blame", "branch", "checkout", "clean", "color", "column",
+	"commit", "core", "credential", "delta", "diff", "difftool", "fetch", "filter", "format",
+	"gc", "gpg", "grep", "help", "http", "init", "interactive", "lfs", "log", "maintenance",
+	"merge", "mergetool", "notes", "pack", "pager", "protocol", "pull", "push", "rebase",
+	"receive", "remote", "rerere", "safe", "sendemail", "sequence", "stash", "status",
+	"submodule", "tag", "trailer", "transfer", "web", "worktree",
+}
+
+// ValidateFile validates a configuration file. A missing file has no
+// diagnostics.
+func ValidateFile(configFile string) (Diagnostics, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	return Validate(data), nil
+}
+
+// Validate checks the content of config.yaml. Errors are YAML syntax errors,
+// values of the wrong type, duplicate keys and profiles, invalid settings and
+// broken extends chains; warnings are unknown keys and profiles without a
+// user name or email.
+func Validate(data []byte) Diagnostics {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return yamlDiagnostics(err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	v := &validator{}
+	v.root(doc.Content[0])
+
+	if len(v.diagnostics.Errors()) == 0 {
+		config := NewConfig()
+		if err := doc.Decode(config); err != nil {
+			v.diagnostics = append(v.diagnostics, yamlDiagnostics(err)...)
+		} else {
+			v.identities(config, doc.Content[0])
+		}
+	}
+
+	slices.SortStableFunc(v.diagnostics, func(a, b Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+
+	return v.diagnostics
+}
+
+// validator walks the node tree of config.yaml.
+type validator struct {
+	diagnostics Diagnostics
+}
+
+func (v *validator) report(node *yaml.Node, severity Severity, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// mapping reports whether node is a mapping to check further, reporting
+// other kinds and duplicate keys. Null stands for an empty mapping.
+func (v *validator) mapping(node *yaml.Node, what, entry string) bool {
+	if isNull(node) {
+		return false
+	}
+
+	if node.Kind != yaml.MappingNode {
+		v.report(node, SeverityError, "%s must be a mapping, got %s", what, describe(node))
+
+		return false
+	}
+
+	seen := make(map[string]*yaml.Node)
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if first, exists := seen[key.Value]; exists {
+			v.report(key, SeverityError, "duplicate %s '%s', first defined at line %d", entry, key.Value, first.Line)
+
+			continue
+		}
+
+		seen[key.Value] = key
+	}
+
+	return true
+}
+
+// unknown warns about a key that has no meaning where it is used.
+func (v *validator) unknown(key *yaml.Node, where string, known []string) {
+	v.report(key, SeverityWarning, "unknown key '%s' in %s%s", key.Value, where, suggestion(key.Value, known))
+}
+
+func (v *validator) root(node *yaml.Node) {
+	if !v.mapping(node, "config", "key") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+
+		switch key.Value {
+		case "version":
+			v.version(value)
+		case "settings":
+			v.settings(value)
+		case "global":
+			v.sections(value, "global")
+		case "profiles":
+			v.profiles(value)
+		default:
+			v.unknown(key, "config", rootKeys)
+		}
+	}
+}
+
+func (v *validator) version(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+		v.report(node, SeverityError, "version must be an integer, got %s", describe(node))
+
+		return
+	}
+
+	if version, err := strconv.Atoi(node.Value); err != nil || version < 0 {
+		v.report(node, SeverityError, "invalid config version '%s'", node.Value)
+	} else if version > ConfigVersion {
+		v.report(node, SeverityError,
+			"config version %d is newer than version %d supported by this git-context, upgrade it", version, ConfigVersion)
+	}
+}
+
+func (v *validator) settings(node *yaml.Node) {
+	if !v.mapping(node, "settings", "setting") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+
+		switch key.Value {
+		case "mode":
+			if v.scalar(value, "settings.mode") {
+				if _, err := git.ParseMode(value.Value); err != nil {
+					v.report(value, SeverityError, "%v", err)
+				}
+			}
+		case "backupRetention":
+			if value.Kind != yaml.ScalarNode || value.Tag != "!!int" {
+				v.report(value, SeverityError, "settings.backupRetention must be an integer, got %s", describe(value))
+			}
+		case "symlinks":
+			if v.scalar(value, "settings.symlinks") {
+				if _, err := git.ParseSymlinkPolicy(value.Value); err != nil {
+					v.report(value, SeverityError, "%v", err)
+				}
+			}
+		default:
+			v.unknown(key, "settings", settingsKeys)
+		}
+	}
+}
+
+func (v *validator) profiles(node *yaml.Node) {
+	if !v.mapping(node, "profiles", "profile") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		v.profile(node.Content[i].Value, resolve(node.Content[i+1]))
+	}
+}
+
+func (v *validator) profile(name string, node *yaml.Node) {
+	where := fmt.Sprintf("profile '%s'", name)
+	if !v.mapping(node, where, "key") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+
+		switch key.Value {
+		case "extends", "directories", "remotes":
+			v.stringList(value, key.Value)
+		case "url":
+			v.urls(value)
+		case "user":
+			v.user(value)
+		default:
+			if hint := suggestion(key.Value, profileKeys); hint != "" && !slices.Contains(gitSections, strings.ToLower(key.Value)) {
+				v.report(key, SeverityWarning, "section '%s' in %s is not a profile key%s", key.Value, where, hint)
+			}
+
+			v.section(key, value)
+		}
+	}
+}
+
+// sections checks the git sections of global.
+func (v *validator) sections(node *yaml.Node, where string) {
+	if !v.mapping(node, where, "section") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+		if key.Value == "url" {
+			v.urls(value)
+		} else {
+			v.section(key, value)
+		}
+	}
+}
+
+// section checks a git section: a mapping of keys to values, lists of values
+// or subsections.
+func (v *validator) section(key, node *yaml.Node) {
+	if !v.mapping(node, fmt.Sprintf("section '%s'", key.Value), "key") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		entry, value := node.Content[i], resolve(node.Content[i+1])
+
+		switch value.Kind {
+		case yaml.MappingNode:
+			v.section(entry, value)
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				if item = resolve(item); item.Kind != yaml.ScalarNode {
+					v.report(item, SeverityError, "values of '%s' must be scalars, got %s", entry.Value, describe(item))
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) user(node *yaml.Node) {
+	if !v.mapping(node, "user", "key") {
+		return
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolve(node.Content[i+1])
+
+		// Git keys are case-insensitive, signingKey is the documented spelling
+		switch strings.ToLower(key.Value) {
+		case "name", "email", "signingkey":
+			v.scalar(value, "user."+key.Value)
+		default:
+			known := slices.ContainsFunc(userKeys, func(userKey string) bool {
+				return strings.EqualFold(userKey, key.Value)
+			})
+
+			if !known && suggestion(key.Value, userKeys) != "" {
+				v.unknown(key, "user", userKeys)
+			}
+		}
+	}
+}
+
+func (v *validator) urls(node *yaml.Node) {
+	if isNull(node) {
+		return
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		v.report(node, SeverityError, "url must be a list of pattern and insteadOf entries, got %s", describe(node))
+
+		return
+	}
+
+	for _, item := range node.Content {
+		item = resolve(item)
+		if isNull(item) {
+			v.report(item, SeverityError, "url entry must have a pattern and an insteadOf")
+
+			continue
+		}
+
+		if !v.mapping(item, "url entry", "key") {
+			continue
+		}
+
+		for _, name := range urlKeys {
+			if value := mappingValue(item, name); value == nil {
+				v.report(item, SeverityError, "url entry is missing %s", name)
+			} else {
+				v.scalar(resolve(value), "url "+name)
+			}
+		}
+
+		for i := 0; i < len(item.Content); i += 2 {
+			if key := item.Content[i]; !slices.Contains(urlKeys, key.Value) {
+				v.unknown(key, "url entry", urlKeys)
+			}
+		}
+	}
+}
+
+// stringList checks a list of strings.
+func (v *validator) stringList(node *yaml.Node, what string) {
+	if isNull(node) {
+		return
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		v.report(node, SeverityError, "%s must be a list, got %s", what, describe(node))
+
+		return
+	}
+
+	for _, item := range node.Content {
+		v.scalar(resolve(item), what+" entry")
+	}
+}
+
+// scalar reports whether node is a single value, reporting other kinds.
+func (v *validator) scalar(node *yaml.Node, what string) bool {
+	if node.Kind != yaml.ScalarNode {
+		v.report(node, SeverityError, "%s must be a value, got %s", what, describe(node))
+
+		return false
+	}
+
+	return true
+}
+
+// identities checks that the profiles resolve, and that those used directly,
+// rather than only extended, have a user name and email.
+func (v *validator) identities(config *Config, root *yaml.Node) {
+	profiles := mappingValue(root, "profiles")
+	if profiles == nil || resolve(profiles).Kind != yaml.MappingNode {
+		return
+	}
+
+	extended := make(map[string]bool)
+	for _, profile := range config.Profiles {
+		if profile != nil {
+			for _, parent := range profile.Extends {
+				extended[parent] = true
+			}
+		}
+	}
+
+	profiles = resolve(profiles)
+
+	for i := 0; i < len(profiles.Content); i += 2 {
+		key := profiles.Content[i]
+		if config.Profiles[key.Value] == nil {
+			v.report(key, SeverityWarning, "profile '%s' is empty", key.Value)
+
+			continue
+		}
+
+		merged, err := config.Merge(key.Value)
+		if err != nil {
+			v.report(key, SeverityError, "%v", err)
+
+			continue
+		}
+
+		if extended[key.Value] {
+			continue
+		}
+
+		if merged.User.Name == "" {
+			v.report(key, SeverityWarning, "profile '%s' has no user.name", key.Value)
+		}
+
+		if merged.User.Email == "" {
+			v.report(key, SeverityWarning, "profile '%s' has no user.email", key.Value)
+		}
+	}
+}
+
+// yamlDiagnostics converts the errors of the YAML parser and decoder, which
+// only carry a line number.
+func yamlDiagnostics(err error) Diagnostics {
+	messages := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	diagnostics := make(Diagnostics, 0, len(messages))
+
+	for _, message := range messages {
+		message = strings.TrimPrefix(message, "yaml: ")
+		diagnostic := Diagnostic{Line: 1, Severity: SeverityError, Message: message}
+
+		var line int
+		if _, err := fmt.Sscanf(message, "line %d:", &line); err == nil {
+			_, diagnostic.Message, _ = strings.Cut(message, ": ")
+			diagnostic.Line = line
+		}
+
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	return diagnostics
+}
+
+// suggestion returns a hint naming the known key closest to name, if any is
+// close enough to be a typo.
+func suggestion(name string, known []string) string {
+	best, bestDistance := "", 3
+
+	for _, candidate := range known {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	if best == "" || best == name {
+		return ""
+	}
+
+	return fmt.Sprintf(", did you mean '%s'?", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+// resolve returns the node an alias refers to.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// isNull reports whether node is null, written or implied.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// describe names the kind of a node in diagnostics.
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		if isNull(node) {
+			return "null"
+		}
+
+		return fmt.Sprintf("'%s'", node.Value)
+	default:
+		return "an alias"
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "valid",
+			source: `settings:
+  mode: include
+  backupRetention: 5
+global:
+  core:
+    editor: vim
+  url:
+    - pattern: "git@github.com:"
+      insteadOf: https://github.com/
+profiles:
+  base:
+    core:
+      autocrlf: input
+  work:
+    extends: [base]
+    directories: [~/work]
+    user:
+      name: Jane
+      email: jane@work.example
+      signingKey: ~/.ssh/id_ed25519.pub
+      useConfigOnly: true
+    credential "https://github.com":
+      helper: ["", store]
+`,
+		},
+		{
+			name:   "empty file",
+			source: "",
+		},
+		{
+			name:   "syntax error",
+			source: "profiles:\n  work: [\n",
+			want:   []string{"2:0 error: did not find expected node content"},
+		},
+		{
+			name: "unknown keys",
+			source: `setings: {}
+settings:
+  retention: 3
+profiles:
+  work:
+    usr:
+      email: jane@work.example
+    remote:
+      pushDefault: origin
+    user:
+      name: Jane
+      email: jane@work.example
+      emial: jane@work.example
+`,
+			want: []string{
+				"1:1 warning: unknown key 'setings' in config, did you mean 'settings'?",
+				"3:3 warning: unknown key 'retention' in settings",
+				"6:5 warning: section 'usr' in profile 'work' is not a profile key, did you mean 'user'?",
+				"13:7 warning: unknown key 'emial' in user, did you mean 'email'?",
+			},
+		},
+		{
+			name: "wrong types",
+			source: `settings:
+  mode: [block]
+  backupRetention: many
+global:
+  core: vim
+profiles:
+  work:
+    extends: base
+    url: {pattern: a, insteadOf: b}
+    user:
+      name: {first: Jane}
+      email: jane@work.example
+`,
+			want: []string{
+				"2:9 error: settings.mode must be a value, got a list",
+				"3:20 error: settings.backupRetention must be an integer, got 'many'",
+				"5:9 error: section 'core' must be a mapping, got 'vim'",
+				"8:14 error: extends must be a list, got 'base'",
+				"9:10 error: url must be a list of pattern and insteadOf entries, got a mapping",
+				"11:13 error: user.name must be a value, got a mapping",
+			},
+		},
+		{
+			name: "invalid settings and url entries",
+			source: `settings:
+  mode: blocks
+  symlinks: replace
+global:
+  url:
+    - pattern: a
+      insteadof: b
+profiles: {}
+`,
+			want: []string{
+				"2:9 error: unknown write mode 'blocks'",
+				"3:13 error: unknown symlink policy 'replace'",
+				"6:7 error: url entry is missing insteadOf",
+				"7:7 warning: unknown key 'insteadof' in url entry, did you mean 'insteadOf'?",
+			},
+		},
+		{
+			name: "duplicate profiles",
+			source: `profiles:
+  work:
+    user: {name: Jane, email: jane@work.example}
+  work:
+    user: {name: Jane, email: jane@corp.example}
+`,
+			want: []string{"4:3 error: duplicate profile 'work', first defined at line 2"},
+		},
+		{
+			name: "empty identities",
+			source: `global:
+  user:
+    name: Jane
+profiles:
+  base:
+    core:
+      editor: vim
+  work:
+    extends: [base]
+  home:
+`,
+			want: []string{
+				"8:3 warning: profile 'work' has no user.email",
+				"10:3 warning: profile 'home' is empty",
+			},
+		},
+		{
+			name: "unknown parent",
+			source: `profiles:
+  work:
+    extends: [bsae]
+    user: {name: Jane, email: jane@work.example}
+`,
+			want: []string{"2:3 error: profile 'work' extends unknown profile 'bsae'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, diagnostic := range Validate([]byte(tt.source)) {
+				got = append(got, formatDiagnostic(diagnostic))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func formatDiagnostic(d Diagnostic) string {
+	return fmt.Sprintf("%d:%d %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	t.Parallel()
+
+	diagnostic := Diagnostic{Line: 3, Column: 5, Severity: SeverityError, Message: "bad"}
+	if got := diagnostic.Format("config.yaml"); got != "config.yaml:3:5: bad" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	diagnostic.Column = 0
+	if got := diagnostic.Format("config.yaml"); got != "config.yaml:3: bad" {
+		t.Errorf("Format() = %q", got)
+	}
+}
+
+func TestLoadConfigValidationError(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("profiles:\n  work:\n    url: {pattern: a}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, err := LoadConfig(configFile, "")
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+
+	want := configFile + ":3:10: url must be a list of pattern and insteadOf entries, got a mapping"
+	if validationErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", validationErr.Error(), want)
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"usr", ", did you mean 'user'?"},
+		{"User", ", did you mean 'user'?"},
+		{"user", ""},
+		{"delta", ""},
+	}
+
+	for _, tt := range tests {
+		if got := suggestion(tt.name, profileKeys); got != tt.want {
+			t.Errorf("suggestion(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fsutil
+
+import (
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// lockRetryInterval is how often a held lock is retried.
+const lockRetryInterval = 50 * time.Millisecond
+
+// errLocked reports a lock held by another process.
+var errLocked = errors.New("lock is held by another process")
+
+// Lock is an advisory lock on a lock file, taken by every git-context process
+// that modifies files.
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// Acquire takes the lock at path, waiting up to timeout while another process
+// holds it.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		file, err := tryLock(path)
+		if err == nil {
+			return &Lock{file: file, path: path}, nil
+		}
+
+		if !errors.Is(err, errLocked) {
+			return nil, errors.Wrap(err, "failed to take lock")
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.WithStack(errors.Newf(
+				"timed out waiting for %s, held by another git-context process%s", path, staleLockHint,
+			))
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	if err := unlock(l.file, l.path); err != nil {
+		return errors.Wrap(err, "failed to release lock")
+	}
+
+	return nil
+}
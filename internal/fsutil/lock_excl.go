@@ -0,0 +1,31 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package fsutil
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+)
+
+// staleLockHint explains how to recover from a process that exited holding the lock.
+const staleLockHint = " (remove it if no git-context process is running)"
+
+// tryLock creates path exclusively: its existence is the lock.
+func tryLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, errLocked
+	}
+
+	return file, err
+}
+
+// unlock removes the lock file.
+func unlock(file *os.File, path string) error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,41 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package fsutil
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/cockroachdb/errors"
+)
+
+// staleLockHint is empty: the system releases the lock of a process that exits.
+const staleLockHint = ""
+
+// tryLock takes an flock on path without waiting.
+func tryLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// unlock releases the flock. The lock file is kept, as removing it would let
+// another process lock a new file while a third still waits on the old one.
+func unlock(file *os.File, _ string) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	return errors.CombineErrors(err, file.Close())
+}
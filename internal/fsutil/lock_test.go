@@ -0,0 +1,40 @@
+package fsutil
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "lock")
+
+	lock, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	if _, err := Acquire(path, 100*time.Millisecond); err == nil {
+		t.Fatal("Expected a held lock to time out")
+	}
+
+	// A waiting process gets the lock once it is released
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+
+		if err := lock.Release(); err != nil {
+			t.Errorf("Release failed: %v", err)
+		}
+	}()
+
+	next, err := Acquire(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+
+	if err := next.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package fsutil writes files safely: atomically, and under a lock shared by
+// concurrent git-context processes.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+)
+
+// maxSymlinks bounds the symbolic links followed when resolving a path.
+const maxSymlinks = 40
+
+// WriteFile writes data to path atomically: the data goes to a temporary file
+// in the same directory, which is synced and renamed over path, so readers and
+// a crash see either the old or the new content. An existing file keeps its
+// mode, perm applies to new files. A symbolic link at path is followed, so the
+// file it points to is replaced and the link is kept.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	target, err := ResolveSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return errors.Wrap(err, "failed to stat file")
+	}
+
+	dir := filepath.Dir(target)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+
+	// Once renamed, the temporary file is gone and removing it fails harmlessly
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to sync temporary file")
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+
+		return errors.Wrap(err, "failed to set file mode")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close temporary file")
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return errors.Wrap(err, "failed to replace file")
+	}
+
+	syncDir(dir)
+
+	return nil
+}
+
+// ResolveSymlinks follows the symbolic links at path and returns the path of
+// the file they point to, which may not exist yet.
+func ResolveSymlinks(path string) (string, error) {
+	for range maxSymlinks {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return path, nil
+		}
+
+		if err != nil {
+			return "", errors.Wrap(err, "failed to stat file")
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read symbolic link")
+		}
+
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+
+		path = link
+	}
+
+	return "", errors.WithStack(errors.Newf("too many levels of symbolic links: %s", path))
+}
+
+// syncDir flushes a directory entry change to disk. Not every platform can
+// sync a directory, so this is best effort.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+
+	_ = d.Sync()
+	_ = d.Close()
+}
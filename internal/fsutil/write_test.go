@@ -0,0 +1,87 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+
+	if err := WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a new file to get the given mode, got %v", info.Mode())
+	}
+
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+
+	if err := WriteFile(path, []byte("second"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("Expected the content to be replaced, got %q", data)
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("Expected the existing mode to be kept, got %v", info.Mode())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file to be left, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileFollowsSymlinks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "gitconfig")
+	link := filepath.Join(dir, ".gitconfig")
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join("dotfiles", "gitconfig"), link); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	// A dangling link creates its target
+	if err := WriteFile(link, []byte("content"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the link to be kept, got %v (%v)", info, err)
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "content" {
+		t.Errorf("Expected the link target to be written, got %q", data)
+	}
+}
+
+func TestResolveSymlinksLoop(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	link := filepath.Join(dir, "loop")
+
+	if err := os.Symlink("loop", link); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	if _, err := ResolveSymlinks(link); err == nil {
+		t.Error("Expected a symbolic link loop to be reported")
+	}
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"cmp"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ChangeKind describes how a live value differs from the expected one.
+type ChangeKind string
+
+const (
+	// ChangeAdded is a key set in the live config only.
+	ChangeAdded ChangeKind = "added"
+	// ChangeChanged is a key whose live value differs from the expected one.
+	ChangeChanged ChangeKind = "changed"
+	// ChangeRemoved is an expected key missing from the live config.
+	ChangeRemoved ChangeKind = "removed"
+)
+
+// Change is a difference between expected and live config values.
+type Change struct {
+	Key      string
+	Kind     ChangeKind
+	Expected []string
+	Live     []string
+}
+
+// Diff compares expected values with the effective live values.
+// A key matches when its last live values are the expected ones, since
+// earlier occurrences are overridden. Keys of managed missing from expected
+// are reported as added; keys set elsewhere, such as user content outside a
+// managed block, are not. Changes are sorted by key.
+func Diff(expected, live, managed Values) []Change {
+	var changes []Change
+
+	for _, key := range slices.Sorted(maps.Keys(expected)) {
+		want, got := expected[key], live[key]
+
+		switch {
+		case len(want) == 0:
+			continue
+		case len(got) == 0:
+			changes = append(changes, Change{Key: key, Kind: ChangeRemoved, Expected: want})
+		case len(got) < len(want) || !slices.Equal(got[len(got)-len(want):], want):
+			changes = append(changes, Change{Key: key, Kind: ChangeChanged, Expected: want, Live: got})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(managed)) {
+		if _, exists := expected[key]; !exists {
+			changes = append(changes, Change{Key: key, Kind: ChangeAdded, Live: live[key]})
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	return changes
+}
+
+// ReadManagedValues reads the values of the region git-context writes: the
+// whole global config, its managed block or the included file, depending on
+// the mode. A missing file yields no values.
+func (g *Git) ReadManagedValues() (Values, error) {
+	values := make(Values)
+
+	switch g.mode {
+	case ModeInclude:
+		if err := readValues(g.includePath, values, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	case ModeBlock:
+		data, err := os.ReadFile(g.globalConfigPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return values, nil
+			}
+
+			return nil, errors.Wrap(err, "failed to read git config")
+		}
+
+		start, end, found := findManagedBlock(string(data))
+		if !found {
+			return values, nil
+		}
+
+		file, err := Parse(data[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range file.Entries() {
+			values[entry.Key] = append(values[entry.Key], entry.EffectiveValue())
+		}
+	default:
+		return g.ReadValues()
+	}
+
+	return values, nil
+}
@@ -0,0 +1,145 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	expected := Values{
+		"core.editor":    {"vim"},
+		"user.email":     {"me@example.com"},
+		"safe.directory": {"/a", "/b"},
+		"user.name":      {"Me"},
+	}
+
+	live := Values{
+		"core.editor":    {"vim", "nano"},
+		"user.email":     {"other@example.com", "me@example.com"},
+		"safe.directory": {"/a"},
+		"pull.rebase":    {"true"},
+		"alias.st":       {"status"},
+	}
+
+	// alias.st was set outside the managed region
+	managed := Values{
+		"core.editor":    {"vim", "nano"},
+		"user.email":     {"me@example.com"},
+		"safe.directory": {"/a"},
+		"pull.rebase":    {"true"},
+	}
+
+	changes := Diff(expected, live, managed)
+
+	want := []Change{
+		{Key: "core.editor", Kind: ChangeChanged, Expected: []string{"vim"}, Live: []string{"vim", "nano"}},
+		{Key: "pull.rebase", Kind: ChangeAdded, Live: []string{"true"}},
+		{Key: "safe.directory", Kind: ChangeChanged, Expected: []string{"/a", "/b"}, Live: []string{"/a"}},
+		{Key: "user.name", Kind: ChangeRemoved, Expected: []string{"Me"}},
+	}
+
+	if len(changes) != len(want) {
+		t.Fatalf("Diff() = %v, want %v", changes, want)
+	}
+
+	for i := range want {
+		got := changes[i]
+		if got.Key != want[i].Key || got.Kind != want[i].Kind ||
+			!slices.Equal(got.Expected, want[i].Expected) || !slices.Equal(got.Live, want[i].Live) {
+			t.Errorf("Diff()[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	if changes := Diff(expected, expected, expected); len(changes) != 0 {
+		t.Errorf("Identical values should not differ, got %v", changes)
+	}
+}
+
+func TestReadManagedValues(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".gitconfig")
+	includePath := filepath.Join(dir, "managed")
+
+	content := "[alias]\n\tst = status\n" + blockBegin + "\n[core]\n\teditor = vim\n" + blockEnd + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	if err := os.WriteFile(includePath, []byte("[user]\n\tname = Me\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+
+	tests := []struct {
+		mode Mode
+		want []string
+	}{
+		{ModeOverwrite, []string{"alias.st", "core.editor"}},
+		{ModeBlock, []string{"core.editor"}},
+		{ModeInclude, []string{"user.name"}},
+	}
+
+	for _, tt := range tests {
+		g := NewGit(configPath, WithMode(tt.mode), WithIncludePath(includePath))
+
+		values, err := g.ReadManagedValues()
+		if err != nil {
+			t.Fatalf("ReadManagedValues(%s) failed: %v", tt.mode, err)
+		}
+
+		var keys []string
+		for key := range values {
+			keys = append(keys, key)
+		}
+
+		slices.Sort(keys)
+
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("ReadManagedValues(%s) keys = %v, want %v", tt.mode, keys, tt.want)
+		}
+	}
+
+	// A missing file has no managed values
+	g := NewGit(filepath.Join(dir, "missing"), WithMode(ModeBlock))
+	if values, err := g.ReadManagedValues(); err != nil || len(values) != 0 {
+		t.Errorf("ReadManagedValues() of a missing file = (%v, %v), want empty", values, err)
+	}
+}
+
+func TestDiffValuelessKey(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), ".gitconfig")
+
+	content := "[core]\n\tbare\n" + blockBegin + "\n[pull]\n\trebase\n" + blockEnd + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write git config: %v", err)
+	}
+
+	g := NewGit(configPath, WithMode(ModeBlock))
+
+	live, err := g.ReadValues()
+	if err != nil {
+		t.Fatalf("ReadValues failed: %v", err)
+	}
+
+	managed, err := g.ReadManagedValues()
+	if err != nil {
+		t.Fatalf("ReadManagedValues failed: %v", err)
+	}
+
+	// A key written without '=' is boolean true, as git reads it
+	if got := live.Get("core.bare"); got != "true" {
+		t.Errorf("Expected core.bare 'true', got %q", got)
+	}
+
+	expected := Values{"core.bare": {"true"}, "pull.rebase": {"true"}}
+	if changes := Diff(expected, live, managed); len(changes) != 0 {
+		t.Errorf("Valueless keys should not drift, got %v", changes)
+	}
+}
@@ -1,36 +1,280 @@
 package git
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/aanogueira/git-context/internal/fsutil"
 	"github.com/cockroachdb/errors"
 )
 
+// Mode defines how git-context writes to the global git config.
+type Mode string
+
+const (
+	// ModeOverwrite replaces the whole global git config on every write.
+	ModeOverwrite Mode = "overwrite"
+	// ModeInclude writes the generated config to a separate file and keeps
+	// a single include entry for it in the global git config.
+	ModeInclude Mode = "include"
+	// ModeBlock writes the generated config inside a delimited marker block
+	// of the global git config, leaving everything else untouched.
+	ModeBlock Mode = "block"
+)
+
+// Markers delimiting the region of a git config owned by git-context.
+const (
+	blockBegin = "# BEGIN git-context managed block (do not edit, changes are overwritten)"
+	blockEnd   = "# END git-context managed block"
+)
+
+// ParseMode converts a mode name into a Mode, defaulting to ModeOverwrite.
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeOverwrite:
+		return ModeOverwrite, nil
+	case ModeInclude, ModeBlock:
+		return Mode(name), nil
+	default:
+		return "", errors.WithStack(errors.Newf("unknown write mode '%s'", name))
+	}
+}
+
 // Git handles git config operations.
 type Git struct {
 	globalConfigPath string
+	mode             Mode
+	includePath      string
+	symlinks         SymlinkPolicy
+}
+
+// Option configures a Git instance.
+type Option func(*Git)
+
+// WithMode sets how the global git config is written.
+func WithMode(mode Mode) Option {
+	return func(g *Git) {
+		g.mode = mode
+	}
+}
+
+// WithIncludePath sets the file holding the generated config in ModeInclude.
+func WithIncludePath(path string) Option {
+	return func(g *Git) {
+		g.includePath = path
+	}
 }
 
 // NewGit creates a new Git instance.
-func NewGit(globalConfigPath string) *Git {
-	return &Git{
+func NewGit(globalConfigPath string, opts ...Option) *Git {
+	g := &Git{
 		globalConfigPath: globalConfigPath,
+		mode:             ModeOverwrite,
+		symlinks:         SymlinkFollow,
+	}
+
+	for _, opt := range opts {
+		opt(g)
 	}
+
+	return g
 }
 
 // WriteConfig writes configuration to git global config.
-func (g *Git) WriteConfig(config map[string]any) error {
-	content := buildGitConfig(config)
-	if err := os.WriteFile(g.globalConfigPath, []byte(content), 0o644); err != nil {
+// Depending on the mode, the whole file is replaced or only the managed region.
+// Sections listed in sectionOrder are written first, see buildGitConfig.
+func (g *Git) WriteConfig(config map[string]any, sectionOrder ...string) error {
+	return g.write(buildGitConfig(config, sectionOrder...))
+}
+
+// FormatConfig returns config in git config format, as WriteConfig writes it.
+func FormatConfig(config map[string]any, sectionOrder ...string) string {
+	return buildGitConfig(config, sectionOrder...)
+}
+
+// WriteConfigWithIncludes is like WriteConfig and appends includes after the
+// generated sections, in the given order. Git applies included files in file
+// order, so for a key set by several matching includes the last one wins.
+func (g *Git) WriteConfigWithIncludes(config map[string]any, includes []Include, sectionOrder ...string) error {
+	return g.write(buildGitConfig(config, sectionOrder...) + buildIncludes(includes))
+}
+
+// write stores content according to the mode and the symlink policy.
+func (g *Git) write(content string) error {
+	if err := g.checkSymlinks(); err != nil {
+		return err
+	}
+
+	switch g.mode {
+	case ModeInclude:
+		if g.includePath == "" {
+			return errors.New("include mode requires an include path")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(g.includePath), 0o755); err != nil {
+			return errors.Wrap(err, "failed to create include directory")
+		}
+
+		if err := fsutil.WriteFile(g.includePath, []byte(content), 0o644); err != nil {
+			return errors.Wrap(err, "failed to write included git config")
+		}
+
+		return g.writeManagedBlock(fmt.Sprintf("[include]\n\tpath = %s\n", quoteValue(g.includePath)))
+	case ModeBlock:
+		return g.writeManagedBlock(content)
+	default:
+		if err := fsutil.WriteFile(g.globalConfigPath, []byte(content), 0o644); err != nil {
+			return errors.Wrap(err, "failed to write git config")
+		}
+
+		return nil
+	}
+}
+
+// writeManagedBlock replaces the managed block of the global git config with
+// content, appending the block if the file does not contain one yet.
+func (g *Git) writeManagedBlock(content string) error {
+	existing, err := os.ReadFile(g.globalConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read git config")
+	}
+
+	updated := replaceManagedBlock(string(existing), content)
+	if err := fsutil.WriteFile(g.globalConfigPath, []byte(updated), 0o644); err != nil {
 		return errors.Wrap(err, "failed to write git config")
 	}
 
 	return nil
 }
 
-// BackupConfig creates a backup of the git config.
+// replaceManagedBlock returns existing with its managed block set to content.
+// Text outside the markers is kept byte-for-byte.
+func replaceManagedBlock(existing, content string) string {
+	block := blockBegin + "\n" + strings.TrimRight(content, "\n") + "\n" + blockEnd + "\n"
+
+	start, end, found := findManagedBlock(existing)
+	if found {
+		return existing[:start] + block + existing[end:]
+	}
+
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+
+	return existing + block
+}
+
+// findManagedBlock locates the managed block in content.
+// It returns the byte offsets of the begin marker line and the end of the end
+// marker line (including its newline).
+func findManagedBlock(content string) (int, int, bool) {
+	start := -1
+	offset := 0
+
+	for line := range strings.SplitAfterSeq(content, "\n") {
+		next := offset + len(line)
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case start < 0 && trimmed == blockBegin:
+			start = offset
+		case start >= 0 && trimmed == blockEnd:
+			return start, next, true
+		}
+
+		offset = next
+	}
+
+	return 0, 0, false
+}
+
+// Values maps canonical config keys to their values in file order.
+type Values map[string][]string
+
+// Get returns the value git uses for key once the config and its includes
+// are read: the last one seen. It returns an empty string when key is unset.
+func (v Values) Get(key string) string {
+	values := v[CanonicalKey(key)]
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// maxIncludeDepth mirrors git's limit on nested includes.
+const maxIncludeDepth = 10
+
+// ReadValues reads the global git config, following include directives.
+// Conditional includes are skipped since they depend on the repository git
+// runs in. A missing config file yields no values.
+func (g *Git) ReadValues() (Values, error) {
+	values := make(Values)
+
+	if err := readValues(g.globalConfigPath, values, 0); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// readValues adds the values of the config file at path to values.
+func readValues(path string, values Values, depth int) error {
+	if depth > maxIncludeDepth {
+		return errors.WithStack(errors.Newf("exceeded maximum include depth at %s", path))
+	}
+
+	file, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range file.Entries() {
+		if entry.Key != "include.path" {
+			values[entry.Key] = append(values[entry.Key], entry.EffectiveValue())
+
+			continue
+		}
+
+		included := ResolveIncludePath(path, entry.Value)
+		if err := readValues(included, values, depth+1); err != nil &&
+			!errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveIncludePath resolves an include path the way git does: "~/" expands
+// to the home directory and relative paths are relative to the including file.
+func ResolveIncludePath(configPath, includePath string) string {
+	if rest, ok := strings.CutPrefix(includePath, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+
+	if filepath.IsAbs(includePath) {
+		return includePath
+	}
+
+	return filepath.Join(filepath.Dir(configPath), includePath)
+}
+
+// BackupConfig creates a backup of the git config. When the config is a
+// symbolic link, the content of the file it points to is copied, so the
+// backup is a regular file whatever the symlink policy.
 func (g *Git) BackupConfig(backupPath string) error {
 	data, err := os.ReadFile(g.globalConfigPath)
 	if err != nil {
@@ -41,65 +285,155 @@ func (g *Git) BackupConfig(backupPath string) error {
 		return errors.Wrap(err, "failed to read git config for backup")
 	}
 
-	if err := os.WriteFile(backupPath, data, 0o644); err != nil {
+	if err := fsutil.WriteFile(backupPath, data, 0o644); err != nil {
 		return errors.Wrap(err, "failed to create backup")
 	}
 
 	return nil
 }
 
+// configSection groups the values of one section for buildGitConfig.
+type configSection struct {
+	name       string
+	subsection string
+	values     map[string][]string
+	keys       []string
+}
+
 // buildGitConfig builds git config format from a map of key-value pairs.
 // It handles both regular dotted notation and quoted subsections.
-// Returns a formatted git config string with sections and key-value pairs.
-//
-//nolint:nestif
-func buildGitConfig(config map[string]any) string {
-	var content strings.Builder
+// Sections whose name appears in sectionOrder come first in that order, the
+// others follow alphabetically; keys are sorted within each section so the
+// output is stable. Returns a formatted git config string.
+func buildGitConfig(config map[string]any, sectionOrder ...string) string {
+	sections := make(map[string]*configSection)
+
+	keys := slices.Sorted(maps.Keys(config))
+	for _, key := range keys {
+		section, subsection, name, ok := SplitKey(key)
+		if !ok {
+			continue
+		}
+
+		// Section names are case-insensitive and written in canonical form
+		section = strings.ToLower(section)
 
-	sectionMap := make(map[string]map[string]any)
-
-	// Group keys by section
-	for key, value := range config {
-		// Handle quoted subsections like url."pattern".insteadof
-		var section, keyName string
-
-		if strings.Contains(key, "\"") {
-			// This is a quoted subsection like url."ssh://git@gitlab.molops.io/".insteadof
-			// Extract the section with the quoted part intact
-			lastDotIdx := strings.LastIndex(key, ".")
-			if lastDotIdx > 0 {
-				section = key[:lastDotIdx]
-				keyName = key[lastDotIdx+1:]
-			} else {
-				continue
+		id := section + "\x00" + subsection
+		if sections[id] == nil {
+			sections[id] = &configSection{
+				name:       section,
+				subsection: subsection,
+				values:     make(map[string][]string),
 			}
+		}
+
+		// Variable names are case-insensitive: the last spelling in sorted
+		// order wins, so the result does not depend on map iteration
+		s := sections[id]
+		lower := strings.ToLower(name)
+
+		if idx := slices.IndexFunc(s.keys, func(k string) bool { return strings.ToLower(k) == lower }); idx >= 0 {
+			s.keys = slices.Delete(s.keys, idx, idx+1)
+		}
+
+		s.keys = append(s.keys, name)
+		s.values[lower] = formatValues(config[key])
+	}
+
+	rank := func(s *configSection) int {
+		if idx := slices.Index(sectionOrder, strings.ToLower(s.name)); idx >= 0 {
+			return idx
+		}
+
+		return len(sectionOrder)
+	}
+
+	ordered := slices.SortedFunc(maps.Values(sections), func(a, b *configSection) int {
+		return cmp.Or(
+			cmp.Compare(rank(a), rank(b)),
+			cmp.Compare(strings.ToLower(a.name), strings.ToLower(b.name)),
+			cmp.Compare(a.subsection, b.subsection),
+		)
+	})
+
+	var content strings.Builder
+
+	for _, s := range ordered {
+		if s.subsection != "" {
+			content.WriteString(fmt.Sprintf("[%s %s]\n", s.name, quoteSubsection(s.subsection)))
 		} else {
-			// Regular dot notation
-			parts := strings.Split(key, ".")
-			if len(parts) >= 2 {
-				section = strings.Join(parts[:len(parts)-1], ".")
-				keyName = parts[len(parts)-1]
-			} else {
-				continue
+			content.WriteString(fmt.Sprintf("[%s]\n", s.name))
+		}
+
+		slices.SortFunc(s.keys, func(a, b string) int {
+			return cmp.Or(cmp.Compare(strings.ToLower(a), strings.ToLower(b)), cmp.Compare(a, b))
+		})
+
+		for _, k := range s.keys {
+			for _, v := range s.values[strings.ToLower(k)] {
+				content.WriteString(fmt.Sprintf("\t%s = %s\n", k, quoteValue(v)))
 			}
 		}
 
-		if sectionMap[section] == nil {
-			sectionMap[section] = make(map[string]any)
+		content.WriteString("\n")
+	}
+
+	return content.String()
+}
+
+// ConfigValues returns the values WriteConfig writes for config, keyed by
+// canonical key, so they can be compared with the values read from a file.
+func ConfigValues(config map[string]any) Values {
+	values := make(Values)
+
+	// Sorted like buildGitConfig, so the same spelling wins among case variants
+	for _, key := range slices.Sorted(maps.Keys(config)) {
+		if _, _, _, ok := SplitKey(key); !ok {
+			continue
+		}
+
+		values[CanonicalKey(key)] = formatValues(config[key])
+	}
+
+	return values
+}
+
+// formatValues converts a config value into its git representation.
+// Lists yield one value per element, nil yields none.
+func formatValues(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		var values []string
+		for _, item := range v {
+			values = append(values, formatValues(item)...)
 		}
 
-		sectionMap[section][keyName] = value
+		return values
+	case []string:
+		return slices.Clone(v)
+	case string:
+		return []string{v}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	default:
+		return []string{fmt.Sprintf("%v", v)}
 	}
+}
 
-	// Write sections
-	for section, values := range sectionMap {
-		content.WriteString(fmt.Sprintf("[%s]\n", section))
+// buildIncludes formats include and includeIf sections, one per include.
+func buildIncludes(includes []Include) string {
+	var content strings.Builder
 
-		for k, v := range values {
-			content.WriteString(fmt.Sprintf("\t%s = %v\n", k, v))
+	for _, include := range includes {
+		if include.Condition == "" {
+			content.WriteString("[include]\n")
+		} else {
+			content.WriteString(fmt.Sprintf("[includeIf %s]\n", quoteSubsection(include.Condition)))
 		}
 
-		content.WriteString("\n")
+		content.WriteString(fmt.Sprintf("\tpath = %s\n\n", quoteValue(include.Path)))
 	}
 
 	return content.String()
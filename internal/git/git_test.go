@@ -3,6 +3,7 @@ package git
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -273,11 +274,357 @@ func TestBuildGitConfigWithNestedDotNotation(t *testing.T) {
 
 	content := buildGitConfig(config)
 
-	if !strings.Contains(content, "[delta.decorations]") {
+	if !strings.Contains(content, `[delta "decorations"]`) {
 		t.Error("Config should contain nested section")
 	}
 
-	if !strings.Contains(content, "[delta.interactive]") {
+	if !strings.Contains(content, `[delta "interactive"]`) {
 		t.Error("Config should contain nested section")
 	}
 }
+
+func TestBuildGitConfigDeterministic(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"user.name":        "Test User",
+		"user.email":       "test@example.com",
+		"core.editor":      "vim",
+		"core.autocrlf":    "input",
+		"push.default":     "simple",
+		"alias.st":         "status",
+		"alias.co":         "checkout",
+		"delta.navigate":   true,
+		"delta.line.style": "bold",
+		"url \"ssh://git@github.com/\".insteadOf": "https://github.com/",
+	}
+
+	want := `[core]
+	autocrlf = input
+	editor = vim
+
+[push]
+	default = simple
+
+[alias]
+	co = checkout
+	st = status
+
+[delta]
+	navigate = true
+
+[delta "line"]
+	style = bold
+
+[url "ssh://git@github.com/"]
+	insteadOf = https://github.com/
+
+[user]
+	email = test@example.com
+	name = Test User
+
+`
+
+	for range 20 {
+		if got := buildGitConfig(config, "core", "push"); got != want {
+			t.Fatalf("buildGitConfig output mismatch:\nwant:\n%s\ngot:\n%s", want, got)
+		}
+	}
+}
+
+func TestBuildGitConfigEscaping(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"alias.hash":                         "log --format=%h # short",
+		"alias.semi":                         "!f() { git status; }; f",
+		"core.padded":                        "  spaced  ",
+		"core.windows":                       `C:\tools\editor.exe`,
+		"core.quoted":                        `say "hi"`,
+		"http.postBuffer":                    524288000,
+		"pull.rebase":                        false,
+		"safe.directory":                     []any{"/srv/a", "/srv/b"},
+		"url \"https://host/a b\".insteadOf": "ab:",
+	}
+
+	content := buildGitConfig(config)
+
+	file, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("Generated config should parse: %v\n%s", err, content)
+	}
+
+	checks := map[string]string{
+		"alias.hash":                     "log --format=%h # short",
+		"alias.semi":                     "!f() { git status; }; f",
+		"core.padded":                    "  spaced  ",
+		"core.windows":                   `C:\tools\editor.exe`,
+		"core.quoted":                    `say "hi"`,
+		"http.postbuffer":                "524288000",
+		"pull.rebase":                    "false",
+		"url.https://host/a b.insteadof": "ab:",
+	}
+
+	for key, want := range checks {
+		if got, _ := file.Get(key); got != want {
+			t.Errorf("Value of %s = %q, want %q", key, got, want)
+		}
+	}
+
+	if got := file.GetAll("safe.directory"); !slices.Equal(got, []string{"/srv/a", "/srv/b"}) {
+		t.Errorf("List values should be written as repeated keys, got %v", got)
+	}
+
+	if strings.Contains(content, "[/srv/a /srv/b]") {
+		t.Error("List values should not be formatted as a single Go slice")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    Mode
+		wantErr bool
+	}{
+		{"Empty defaults to overwrite", "", ModeOverwrite, false},
+		{"Overwrite", "overwrite", ModeOverwrite, false},
+		{"Include", "include", ModeInclude, false},
+		{"Block", "block", ModeBlock, false},
+		{"Unknown", "replace", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseMode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteConfigBlockModePreservesUserContent(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.gitconfig")
+
+	before := "# my settings\n[safe]\n\tdirectory = /srv/repo\n"
+	after := "[credential]\n\thelper = store"
+
+	if err := os.WriteFile(configPath, []byte(before+after), 0o644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	g := NewGit(configPath, WithMode(ModeBlock))
+
+	if err := g.WriteConfig(map[string]any{"user.name": "First"}); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	if !strings.HasPrefix(string(content), before+after+"\n"+blockBegin+"\n") {
+		t.Errorf("User content should be kept and block appended, got:\n%s", content)
+	}
+
+	// Move the block between user content and switch again
+	moved := before + string(content)[len(before+after)+1:] + after
+	if err := os.WriteFile(configPath, []byte(moved), 0o644); err != nil {
+		t.Fatalf("Failed to rewrite config: %v", err)
+	}
+
+	if err := g.WriteConfig(map[string]any{"user.name": "Second"}); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	content, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	contentStr := string(content)
+
+	if !strings.HasPrefix(contentStr, before+blockBegin+"\n") {
+		t.Errorf("Content before block should survive byte-for-byte, got:\n%s", contentStr)
+	}
+
+	if !strings.HasSuffix(contentStr, blockEnd+"\n"+after) {
+		t.Errorf("Content after block should survive byte-for-byte, got:\n%s", contentStr)
+	}
+
+	if strings.Contains(contentStr, "First") || !strings.Contains(contentStr, "name = Second") {
+		t.Errorf("Managed block should be replaced in place, got:\n%s", contentStr)
+	}
+
+	if strings.Count(contentStr, blockBegin) != 1 {
+		t.Error("Config should contain exactly one managed block")
+	}
+}
+
+func TestWriteConfigIncludeMode(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test.gitconfig")
+	includePath := filepath.Join(tmpDir, "git-context", "gitconfig")
+
+	user := "[safe]\n\tdirectory = *\n"
+	if err := os.WriteFile(configPath, []byte(user), 0o644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	g := NewGit(configPath, WithMode(ModeInclude), WithIncludePath(includePath))
+
+	for _, name := range []string{"First", "Second"} {
+		if err := g.WriteConfig(map[string]any{"user.name": name}); err != nil {
+			t.Fatalf("WriteConfig failed: %v", err)
+		}
+	}
+
+	included, err := os.ReadFile(includePath)
+	if err != nil {
+		t.Fatalf("Failed to read included config: %v", err)
+	}
+
+	if !strings.Contains(string(included), "name = Second") {
+		t.Errorf("Included file should hold the generated config, got:\n%s", included)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	contentStr := string(content)
+
+	if !strings.HasPrefix(contentStr, user) {
+		t.Errorf("User content should be preserved, got:\n%s", contentStr)
+	}
+
+	if strings.Count(contentStr, "path = "+includePath) != 1 {
+		t.Errorf("Config should contain a single include entry, got:\n%s", contentStr)
+	}
+
+	if strings.Contains(contentStr, "name =") {
+		t.Error("Generated values should not be written to the global config in include mode")
+	}
+}
+
+func TestWriteConfigIncludeModeRequiresPath(t *testing.T) {
+	t.Parallel()
+
+	g := NewGit(filepath.Join(t.TempDir(), "test.gitconfig"), WithMode(ModeInclude))
+
+	if err := g.WriteConfig(map[string]any{"user.name": "Test"}); err == nil {
+		t.Error("WriteConfig should fail in include mode without an include path")
+	}
+}
+
+func TestReplaceManagedBlockUnterminated(t *testing.T) {
+	t.Parallel()
+
+	// A begin marker without an end marker is treated as user content
+	existing := blockBegin + "\n[user]\n\tname = Old\n"
+
+	result := replaceManagedBlock(existing, "[user]\n\tname = New\n")
+	if !strings.HasPrefix(result, existing) {
+		t.Errorf("Unterminated block should be left untouched, got:\n%s", result)
+	}
+}
+
+func TestBuildGitConfigEmptyResetEntry(t *testing.T) {
+	t.Parallel()
+
+	content := buildGitConfig(map[string]any{
+		"credential.helper": []any{"", "store"},
+	})
+
+	want := "[credential]\n\thelper = \"\"\n\thelper = store\n\n"
+	if content != want {
+		t.Errorf("buildGitConfig() = %q, want %q", content, want)
+	}
+}
+
+func TestBuildGitConfigCaseInsensitiveKeys(t *testing.T) {
+	t.Parallel()
+
+	result := buildGitConfig(map[string]any{
+		"Core.Editor": "vim",
+		"core.editor": "nano",
+	})
+
+	if strings.Count(result, "[core]") != 1 {
+		t.Errorf("Expected a single core section, got:\n%s", result)
+	}
+
+	if strings.Count(strings.ToLower(result), "editor = ") != 1 || !strings.Contains(result, "editor = nano") {
+		t.Errorf("Expected a single editor key, got:\n%s", result)
+	}
+}
+
+func TestWriteConfigWithIncludes(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), ".gitconfig")
+	g := NewGit(configPath)
+
+	includes := []Include{
+		{Condition: "gitdir:~/work/", Path: "/cfg/profiles/work.gitconfig"},
+		{Condition: "hasconfig:remote.*.url:git@github.com:corp/**", Path: "/cfg/profiles/corp.gitconfig"},
+		{Path: "/cfg/extra.gitconfig"},
+	}
+
+	if err := g.WriteConfigWithIncludes(map[string]any{"core.editor": "vim"}, includes); err != nil {
+		t.Fatalf("WriteConfigWithIncludes failed: %v", err)
+	}
+
+	file, err := ParseFile(configPath)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	// Includes keep their order after the generated sections
+	if got := file.Includes(); !slices.Equal(got, includes) {
+		t.Errorf("Includes() = %v, want %v", got, includes)
+	}
+
+	if value, _ := file.Get("core.editor"); value != "vim" {
+		t.Errorf("core.editor = %q, want vim", value)
+	}
+}
+
+func TestConfigValues(t *testing.T) {
+	t.Parallel()
+
+	values := ConfigValues(map[string]any{
+		"user.Email":                            "me@example.com",
+		`url "ssh://git@github.com/".insteadOf`: []any{"https://github.com/", "gh:"},
+		"push.autoSetupRemote":                  true,
+		"invalid":                               "skipped",
+	})
+
+	if values.Get("user.email") != "me@example.com" || values.Get("push.autosetupremote") != "true" {
+		t.Errorf("Unexpected values: %v", values)
+	}
+
+	if got := values["url.ssh://git@github.com/.insteadof"]; !slices.Equal(got, []string{"https://github.com/", "gh:"}) {
+		t.Errorf("Expected both insteadOf values, got %v", got)
+	}
+
+	if len(values) != 3 {
+		t.Errorf("Invalid keys should be skipped, got %v", values)
+	}
+}
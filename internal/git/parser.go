@@ -0,0 +1,687 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// File is a parsed git config file.
+// Lines that are not modified keep their original text, so a parsed file is
+// serialized back byte-for-byte.
+type File struct {
+	// Preamble holds the comments and blank lines before the first section.
+	Preamble []*Line
+	Sections []*Section
+}
+
+// Section is a section of a git config file together with its lines.
+type Section struct {
+	Name          string
+	Subsection    string
+	HasSubsection bool
+	Lines         []*Line
+
+	raw string
+}
+
+// Line is a variable, comment or blank line inside a git config file.
+// Comments and blank lines have an empty Key.
+type Line struct {
+	Key     string
+	Value   string
+	NoValue bool // Variable written without '=', meaning boolean true
+
+	raw string
+}
+
+// Entry is a variable with its canonical key, as returned by File.Entries.
+type Entry struct {
+	Key     string
+	Value   string
+	NoValue bool
+}
+
+// EffectiveValue returns the value git reads for the entry: a variable
+// written without '=' is boolean true.
+func (e Entry) EffectiveValue() string {
+	if e.NoValue {
+		return "true"
+	}
+
+	return e.Value
+}
+
+// Include is an include or includeIf directive of a git config file.
+// Condition is empty for unconditional includes.
+type Include struct {
+	Condition string
+	Path      string
+}
+
+// Parse parses the content of a git config file.
+func Parse(data []byte) (*File, error) {
+	p := &parser{data: string(data)}
+
+	return p.parse()
+}
+
+// ParseFile reads and parses the git config file at path.
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read git config")
+	}
+
+	file, err := Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	return file, nil
+}
+
+// Bytes serializes the file back into git config syntax.
+func (f *File) Bytes() []byte {
+	var content strings.Builder
+
+	write := func(text string, generated bool) {
+		// Generated text must start on its own line, even when the original
+		// file did not end with a newline
+		if generated && content.Len() > 0 && !strings.HasSuffix(content.String(), "\n") {
+			content.WriteString("\n")
+		}
+
+		content.WriteString(text)
+	}
+
+	for _, line := range f.Preamble {
+		write(line.String(), line.raw == "")
+	}
+
+	for _, section := range f.Sections {
+		write(section.header(), section.raw == "")
+
+		for _, line := range section.Lines {
+			write(line.String(), line.raw == "")
+		}
+	}
+
+	return []byte(content.String())
+}
+
+// Entries returns all variables of the file in order, with canonical keys.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+
+	for _, section := range f.Sections {
+		for _, line := range section.Lines {
+			if line.Key == "" {
+				continue
+			}
+
+			entries = append(entries, Entry{
+				Key:     section.canonicalName() + "." + strings.ToLower(line.Key),
+				Value:   line.Value,
+				NoValue: line.NoValue,
+			})
+		}
+	}
+
+	return entries
+}
+
+// Get returns the value of the last line setting key in this file, as
+// written, and whether any does. Included files are not read.
+func (f *File) Get(key string) (string, bool) {
+	values := f.GetAll(key)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	return values[len(values)-1], true
+}
+
+// GetAll returns every value of key in file order.
+func (f *File) GetAll(key string) []string {
+	canonical := CanonicalKey(key)
+
+	var values []string
+
+	for _, entry := range f.Entries() {
+		if entry.Key == canonical {
+			values = append(values, entry.Value)
+		}
+	}
+
+	return values
+}
+
+// Set sets key to value, replacing its last occurrence.
+// If the key does not exist it is added to the last matching section, or to a
+// new section at the end of the file.
+func (f *File) Set(key, value string) error {
+	section, subsection, name, ok := SplitKey(key)
+	if !ok {
+		return errors.WithStack(errors.Newf("invalid config key '%s'", key))
+	}
+
+	canonical := CanonicalKey(key)
+
+	for i := len(f.Sections) - 1; i >= 0; i-- {
+		s := f.Sections[i]
+		for j := len(s.Lines) - 1; j >= 0; j-- {
+			line := s.Lines[j]
+			if line.Key != "" && s.canonicalName()+"."+strings.ToLower(line.Key) == canonical {
+				line.Value = value
+				line.NoValue = false
+				line.raw = ""
+
+				return nil
+			}
+		}
+	}
+
+	f.add(section, subsection, name, value)
+
+	return nil
+}
+
+// Add appends a value for key, keeping any existing values.
+func (f *File) Add(key, value string) error {
+	section, subsection, name, ok := SplitKey(key)
+	if !ok {
+		return errors.WithStack(errors.Newf("invalid config key '%s'", key))
+	}
+
+	f.add(section, subsection, name, value)
+
+	return nil
+}
+
+// add appends a variable to the last matching section, creating it if needed.
+func (f *File) add(section, subsection, name, value string) {
+	target := f.findSection(section, subsection, subsection != "")
+	if target == nil {
+		target = &Section{
+			Name:          section,
+			Subsection:    subsection,
+			HasSubsection: subsection != "",
+		}
+		f.Sections = append(f.Sections, target)
+	}
+
+	target.Lines = append(target.Lines, &Line{Key: name, Value: value})
+}
+
+// Unset removes every occurrence of key and reports whether any was found.
+func (f *File) Unset(key string) bool {
+	canonical := CanonicalKey(key)
+	found := false
+
+	for _, section := range f.Sections {
+		kept := section.Lines[:0]
+
+		for _, line := range section.Lines {
+			if line.Key != "" && section.canonicalName()+"."+strings.ToLower(line.Key) == canonical {
+				found = true
+
+				continue
+			}
+
+			kept = append(kept, line)
+		}
+
+		section.Lines = kept
+	}
+
+	return found
+}
+
+// Includes returns the include and includeIf directives of the file in order.
+func (f *File) Includes() []Include {
+	var includes []Include
+
+	for _, section := range f.Sections {
+		name := strings.ToLower(section.Name)
+		if name != "include" && name != "includeif" {
+			continue
+		}
+
+		for _, line := range section.Lines {
+			if !strings.EqualFold(line.Key, "path") {
+				continue
+			}
+
+			include := Include{Path: line.Value}
+			if name == "includeif" {
+				include.Condition = section.Subsection
+			}
+
+			includes = append(includes, include)
+		}
+	}
+
+	return includes
+}
+
+// findSection returns the last section matching name and subsection.
+func (f *File) findSection(name, subsection string, hasSubsection bool) *Section {
+	for i := len(f.Sections) - 1; i >= 0; i-- {
+		s := f.Sections[i]
+		if strings.EqualFold(s.Name, name) && s.HasSubsection == hasSubsection &&
+			s.Subsection == subsection {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// canonicalName returns the section name as used in canonical keys.
+func (s *Section) canonicalName() string {
+	name := strings.ToLower(s.Name)
+	if s.HasSubsection {
+		name += "." + s.Subsection
+	}
+
+	return name
+}
+
+// header returns the section header line.
+func (s *Section) header() string {
+	if s.raw != "" {
+		return s.raw
+	}
+
+	if s.HasSubsection {
+		return fmt.Sprintf("[%s %s]\n", s.Name, quoteSubsection(s.Subsection))
+	}
+
+	return fmt.Sprintf("[%s]\n", s.Name)
+}
+
+// String returns the line in git config syntax.
+func (l *Line) String() string {
+	if l.raw != "" {
+		return l.raw
+	}
+
+	if l.NoValue {
+		return fmt.Sprintf("\t%s\n", l.Key)
+	}
+
+	return fmt.Sprintf("\t%s = %s\n", l.Key, quoteValue(l.Value))
+}
+
+// CanonicalKey returns key in git's canonical form: the section and variable
+// names are lowercased, the subsection is kept as is.
+func CanonicalKey(key string) string {
+	section, subsection, name, ok := SplitKey(key)
+	if !ok {
+		return strings.ToLower(key)
+	}
+
+	if subsection == "" {
+		return strings.ToLower(section) + "." + strings.ToLower(name)
+	}
+
+	return strings.ToLower(section) + "." + subsection + "." + strings.ToLower(name)
+}
+
+// SplitKey splits a config key into section, subsection and variable name.
+// It accepts both dotted keys (url.ssh://host/.insteadOf) and keys with a
+// quoted subsection (url "ssh://host/".insteadOf).
+func SplitKey(key string) (string, string, string, bool) {
+	if idx := strings.Index(key, " \""); idx > 0 {
+		rest := key[idx+2:]
+
+		end := strings.LastIndex(rest, "\".")
+		if end < 0 || end+2 >= len(rest) {
+			return "", "", "", false
+		}
+
+		subsection := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(rest[:end])
+
+		return key[:idx], subsection, rest[end+2:], true
+	}
+
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", false
+	}
+
+	if first == last {
+		return key[:first], "", key[last+1:], true
+	}
+
+	return key[:first], key[first+1 : last], key[last+1:], true
+}
+
+// quoteValue quotes and escapes a value so git reads it back unchanged.
+// Values are only wrapped in quotes when needed: leading or trailing
+// whitespace, or comment characters.
+func quoteValue(value string) string {
+	escaped := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\t", `\t`,
+		"\b", `\b`,
+	).Replace(value)
+
+	if value == "" || strings.ContainsAny(value, "#;") ||
+		strings.TrimSpace(value) != value {
+		return `"` + escaped + `"`
+	}
+
+	return escaped
+}
+
+// quoteSubsection quotes a subsection name for a section header.
+func quoteSubsection(subsection string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection) + `"`
+}
+
+// parser is a hand-written parser for git's config syntax.
+type parser struct {
+	data string
+	pos  int
+}
+
+func (p *parser) parse() (*File, error) {
+	file := &File{}
+
+	var current *Section
+
+	addLine := func(line *Line) {
+		if current == nil {
+			file.Preamble = append(file.Preamble, line)
+		} else {
+			current.Lines = append(current.Lines, line)
+		}
+	}
+
+	for p.pos < len(p.data) {
+		start := p.pos
+		p.skipBlanks()
+
+		if p.pos >= len(p.data) {
+			addLine(&Line{raw: p.data[start:]})
+
+			break
+		}
+
+		switch c := p.data[p.pos]; {
+		case c == '\n' || c == '#' || c == ';':
+			p.skipLine()
+			addLine(&Line{raw: p.data[start:p.pos]})
+		case c == '[':
+			section, err := p.parseHeader()
+			if err != nil {
+				return nil, err
+			}
+
+			// Keep a trailing comment on the header line with the header
+			lineEnd := p.pos
+			p.skipBlanks()
+
+			if p.pos >= len(p.data) || strings.ContainsRune("\n#;", rune(p.data[p.pos])) {
+				p.skipLine()
+				lineEnd = p.pos
+			}
+
+			p.pos = lineEnd
+			section.raw = p.data[start:p.pos]
+			current = section
+			file.Sections = append(file.Sections, section)
+		case isAlpha(c):
+			if current == nil {
+				return nil, p.errorf("variable outside of a section")
+			}
+
+			line, err := p.parseVariable()
+			if err != nil {
+				return nil, err
+			}
+
+			line.raw = p.data[start:p.pos]
+			current.Lines = append(current.Lines, line)
+		default:
+			return nil, p.errorf("unexpected character %q", c)
+		}
+	}
+
+	return file, nil
+}
+
+// parseHeader parses a section header starting at '['.
+func (p *parser) parseHeader() (*Section, error) {
+	p.pos++ // '['
+
+	start := p.pos
+	for p.pos < len(p.data) && (isAlnum(p.data[p.pos]) || p.data[p.pos] == '-' || p.data[p.pos] == '.') {
+		p.pos++
+	}
+
+	name := p.data[start:p.pos]
+	if name == "" {
+		return nil, p.errorf("empty section name")
+	}
+
+	section := &Section{Name: name}
+
+	// Deprecated [section.subsection] syntax, subsection is lowercased
+	if idx := strings.Index(name, "."); idx >= 0 {
+		section.Name = name[:idx]
+		section.Subsection = strings.ToLower(name[idx+1:])
+		section.HasSubsection = true
+	}
+
+	if p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') && !section.HasSubsection {
+		p.skipBlanks()
+
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.errorf("expected quoted subsection")
+		}
+
+		subsection, err := p.parseSubsection()
+		if err != nil {
+			return nil, err
+		}
+
+		section.Subsection = subsection
+		section.HasSubsection = true
+	}
+
+	if p.pos >= len(p.data) || p.data[p.pos] != ']' {
+		return nil, p.errorf("unterminated section header")
+	}
+
+	p.pos++
+
+	return section, nil
+}
+
+// parseSubsection parses a quoted subsection name starting at '"'.
+func (p *parser) parseSubsection() (string, error) {
+	p.pos++ // opening quote
+
+	var subsection strings.Builder
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return subsection.String(), nil
+		case '\n':
+			return "", p.errorf("newline in subsection name")
+		case '\\':
+			if p.pos >= len(p.data) || p.data[p.pos] == '\n' {
+				return "", p.errorf("newline in subsection name")
+			}
+
+			subsection.WriteByte(p.data[p.pos])
+			p.pos++
+		default:
+			subsection.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated subsection name")
+}
+
+// parseVariable parses a "name = value" line, including continuation lines.
+func (p *parser) parseVariable() (*Line, error) {
+	start := p.pos
+	for p.pos < len(p.data) && (isAlnum(p.data[p.pos]) || p.data[p.pos] == '-') {
+		p.pos++
+	}
+
+	line := &Line{Key: p.data[start:p.pos]}
+
+	p.skipBlanks()
+
+	if p.pos >= len(p.data) || strings.ContainsRune("\n#;", rune(p.data[p.pos])) {
+		line.NoValue = true
+		p.skipLine()
+
+		return line, nil
+	}
+
+	if p.data[p.pos] != '=' {
+		return nil, p.errorf("expected '=' after variable name '%s'", line.Key)
+	}
+
+	p.pos++
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	line.Value = value
+
+	return line, nil
+}
+
+// parseValue parses a variable value up to and including the end of line,
+// handling quotes, escapes, comments and line continuations like git does.
+func (p *parser) parseValue() (string, error) {
+	var value strings.Builder
+
+	quoted := false
+	comment := false
+	spaces := 0
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		if c == '\r' && p.pos < len(p.data) && p.data[p.pos] == '\n' {
+			continue
+		}
+
+		if c == '\n' {
+			if quoted {
+				return "", p.errorf("unterminated quoted value")
+			}
+
+			return value.String(), nil
+		}
+
+		if comment {
+			continue
+		}
+
+		if !quoted && (c == ' ' || c == '\t') {
+			if value.Len() > 0 {
+				spaces++
+			}
+
+			continue
+		}
+
+		if !quoted && (c == '#' || c == ';') {
+			comment = true
+
+			continue
+		}
+
+		for ; spaces > 0; spaces-- {
+			value.WriteByte(' ')
+		}
+
+		switch c {
+		case '\\':
+			if p.pos >= len(p.data) {
+				return "", p.errorf("unexpected end of file after '\\'")
+			}
+
+			escaped := p.data[p.pos]
+			p.pos++
+
+			switch escaped {
+			case '\n':
+				// Line continuation
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			case 'b':
+				value.WriteByte('\b')
+			case '\\', '"':
+				value.WriteByte(escaped)
+			default:
+				return "", p.errorf("invalid escape sequence '\\%c'", escaped)
+			}
+		case '"':
+			quoted = !quoted
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	if quoted {
+		return "", p.errorf("unterminated quoted value")
+	}
+
+	return value.String(), nil
+}
+
+// skipBlanks advances past spaces and tabs.
+func (p *parser) skipBlanks() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t' || p.data[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// skipLine advances past the end of the current line.
+func (p *parser) skipLine() {
+	if idx := strings.IndexByte(p.data[p.pos:], '\n'); idx >= 0 {
+		p.pos += idx + 1
+	} else {
+		p.pos = len(p.data)
+	}
+}
+
+// errorf returns a parse error annotated with the current line number.
+func (p *parser) errorf(format string, args ...any) error {
+	line := strings.Count(p.data[:min(p.pos, len(p.data))], "\n") + 1
+
+	return errors.WithStack(errors.Newf("line %d: %s", line, fmt.Sprintf(format, args...)))
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,372 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `# Global git config
+; managed by hand
+
+[user]
+	name = Test User ; trailing comment
+	email = "test@example.com"
+[core]  # header comment
+	editor = "vim -f"
+	pager = less \
+-R
+	autocrlf
+[url "ssh://git@github.com/"]
+	insteadOf = https://github.com/
+	insteadOf = gh:
+[safe]
+	directory = /srv/a
+	directory = /srv/b
+[delta.Decorations] commit-style = "bold yellow"
+[alias]
+	lg = "log --graph \"--format=%h %s\" # not a comment"
+	tabbed = a\tb
+[include]
+	path = ~/.gitconfig-local
+[includeIf "gitdir:~/work/"]
+	path = .gitconfig-work
+`
+
+func TestParseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	inputs := []string{
+		sampleConfig,
+		"",
+		"\n\n",
+		"[user]\n\tname = No Trailing Newline",
+		"[core]\r\n\teditor = vim\r\n",
+		"  # indented comment\n[a]\n  b = c   \n",
+	}
+
+	for _, input := range inputs {
+		file, err := Parse([]byte(input))
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", input, err)
+		}
+
+		if got := string(file.Bytes()); got != input {
+			t.Errorf("Round trip mismatch:\nwant %q\ngot  %q", input, got)
+		}
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	t.Parallel()
+
+	file, err := Parse([]byte(sampleConfig))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"user.name", "Test User"},
+		{"user.email", "test@example.com"},
+		{"core.editor", "vim -f"},
+		{"core.pager", "less -R"},
+		{"core.autocrlf", ""},
+		{"CORE.Editor", "vim -f"},
+		{"delta.decorations.commit-style", "bold yellow"},
+		{"alias.lg", `log --graph "--format=%h %s" # not a comment`},
+		{"alias.tabbed", "a\tb"},
+		{`url "ssh://git@github.com/".insteadOf`, "gh:"},
+	}
+
+	for _, tt := range tests {
+		got, ok := file.Get(tt.key)
+		if !ok {
+			t.Errorf("Get(%q) not found", tt.key)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	directories := file.GetAll("safe.directory")
+	if !slices.Equal(directories, []string{"/srv/a", "/srv/b"}) {
+		t.Errorf("GetAll(safe.directory) = %v", directories)
+	}
+
+	insteadOf := file.GetAll("url.ssh://git@github.com/.insteadof")
+	if !slices.Equal(insteadOf, []string{"https://github.com/", "gh:"}) {
+		t.Errorf("GetAll(url...insteadof) = %v", insteadOf)
+	}
+
+	for _, entry := range file.Entries() {
+		if entry.Key == "core.autocrlf" && !entry.NoValue {
+			t.Error("core.autocrlf should be parsed as a variable without value")
+		}
+	}
+}
+
+func TestParseIncludes(t *testing.T) {
+	t.Parallel()
+
+	file, err := Parse([]byte(sampleConfig))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []Include{
+		{Condition: "", Path: "~/.gitconfig-local"},
+		{Condition: "gitdir:~/work/", Path: ".gitconfig-work"},
+	}
+
+	if got := file.Includes(); !slices.Equal(got, want) {
+		t.Errorf("Includes() = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	inputs := map[string]string{
+		"Variable outside section": "name = value\n",
+		"Unterminated header":      "[user\n",
+		"Unterminated subsection":  "[url \"abc]\n",
+		"Unterminated quote":       "[user]\n\tname = \"abc\n",
+		"Invalid escape":           "[user]\n\tname = a\\qb\n",
+		"Missing equals":           "[user]\n\tname value\n",
+		"Invalid character":        "[user]\n\t%name = value\n",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := Parse([]byte(input)); err == nil {
+				t.Errorf("Parse(%q) should fail", input)
+			}
+		})
+	}
+}
+
+func TestParseErrorLineNumber(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse([]byte("[user]\n\tname = ok\n\tbad line\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected error on line 3, got: %v", err)
+	}
+}
+
+func TestFileSetAddUnset(t *testing.T) {
+	t.Parallel()
+
+	file, err := Parse([]byte(sampleConfig))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if err := file.Set("user.email", "new@example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if err := file.Set("core.comment", "has # hash"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if err := file.Add("safe.directory", "/srv/c"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if err := file.Set(`credential "https://github.com".helper`, "store"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if !file.Unset("alias.tabbed") {
+		t.Error("Unset should report the removed key")
+	}
+
+	if err := file.Set("invalid", "value"); err == nil {
+		t.Error("Set should fail for a key without section")
+	}
+
+	content := string(file.Bytes())
+
+	// Untouched lines keep their original text
+	for _, unchanged := range []string{
+		"# Global git config\n; managed by hand\n",
+		"\tname = Test User ; trailing comment\n",
+		"[core]  # header comment\n",
+		"\tpager = less \\\n-R\n",
+	} {
+		if !strings.Contains(content, unchanged) {
+			t.Errorf("Expected unchanged text %q in:\n%s", unchanged, content)
+		}
+	}
+
+	// Serialized edits parse back to the same values
+	reparsed, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("Reparse failed: %v\n%s", err, content)
+	}
+
+	checks := map[string]string{
+		"user.email":                           "new@example.com",
+		"core.comment":                         "has # hash",
+		"credential.https://github.com.helper": "store",
+	}
+
+	for key, want := range checks {
+		if got, _ := reparsed.Get(key); got != want {
+			t.Errorf("Get(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	if got := reparsed.GetAll("safe.directory"); len(got) != 3 {
+		t.Errorf("Expected 3 safe.directory values, got %v", got)
+	}
+
+	if _, ok := reparsed.Get("alias.tabbed"); ok {
+		t.Error("alias.tabbed should have been removed")
+	}
+}
+
+func TestFileAddToFileWithoutTrailingNewline(t *testing.T) {
+	t.Parallel()
+
+	file, err := Parse([]byte("[user]\n\tname = Test"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if err := file.Add("user.email", "test@example.com"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	want := "[user]\n\tname = Test\n\temail = test@example.com\n"
+	if got := string(file.Bytes()); got != want {
+		t.Errorf("Bytes() = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"simple", "simple"},
+		{"with space", "with space"},
+		{"", `""`},
+		{" leading", `" leading"`},
+		{"trailing ", `"trailing "`},
+		{"a#b", `"a#b"`},
+		{"a;b", `"a;b"`},
+		{`back\slash`, `back\\slash`},
+		{`say "hi"`, `say \"hi\"`},
+		{"line\nbreak", `line\nbreak`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteValue(tt.input); got != tt.want {
+			t.Errorf("quoteValue(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+
+		// Quoted values parse back to the original
+		file, err := Parse([]byte("[s]\n\tk = " + quoteValue(tt.input) + "\n"))
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", tt.input, err)
+		}
+
+		if got, _ := file.Get("s.k"); got != tt.input {
+			t.Errorf("Round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"user.name":                             "user.name",
+		"Core.AutoCRLF":                         "core.autocrlf",
+		`url "ssh://git@GitHub.com/".insteadOf`: "url.ssh://git@GitHub.com/.insteadof",
+		"url.ssh://git@GitHub.com/.insteadOf":   "url.ssh://git@GitHub.com/.insteadof",
+		"delta.Decorations.Style":               "delta.Decorations.style",
+	}
+
+	for input, want := range tests {
+		if got := CanonicalKey(input); got != want {
+			t.Errorf("CanonicalKey(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestReadValuesFollowsIncludes(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".gitconfig")
+
+	files := map[string]string{
+		".gitconfig": "[user]\n\tname = Base\n[include]\n\tpath = extra\n[includeIf \"gitdir:/work/\"]\n\tpath = work\n[core]\n\teditor = vim\n",
+		"extra":      "[user]\n\tname = Included\n[include]\n\tpath = missing\n",
+		"work":       "[user]\n\temail = work@example.com\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	values, err := NewGit(configPath).ReadValues()
+	if err != nil {
+		t.Fatalf("ReadValues failed: %v", err)
+	}
+
+	if got := values.Get("user.name"); got != "Included" {
+		t.Errorf("Included values should override earlier ones, got %q", got)
+	}
+
+	if got := values.Get("core.editor"); got != "vim" {
+		t.Errorf("Expected core.editor 'vim', got %q", got)
+	}
+
+	if got := values.Get("user.email"); got != "" {
+		t.Errorf("Conditional includes should not be followed, got %q", got)
+	}
+}
+
+func TestReadValuesMissingFile(t *testing.T) {
+	t.Parallel()
+
+	values, err := NewGit(filepath.Join(t.TempDir(), "missing")).ReadValues()
+	if err != nil {
+		t.Fatalf("ReadValues should not fail for a missing file: %v", err)
+	}
+
+	if len(values) != 0 {
+		t.Errorf("Expected no values, got %v", values)
+	}
+}
+
+func TestReadValuesIncludeCycle(t *testing.T) {
+	t.Parallel()
+
+	configPath := filepath.Join(t.TempDir(), ".gitconfig")
+	if err := os.WriteFile(configPath, []byte("[include]\n\tpath = .gitconfig\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := NewGit(configPath).ReadValues(); err == nil {
+		t.Error("ReadValues should fail on recursive includes")
+	}
+}
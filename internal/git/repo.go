@@ -0,0 +1,192 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/cockroachdb/errors"
+)
+
+// Scope selects the repository config file a per-repository switch writes.
+type Scope string
+
+const (
+	// ScopeLocal is the repository config, shared by all its worktrees.
+	ScopeLocal Scope = "local"
+	// ScopeWorktree is the config of the current worktree only.
+	ScopeWorktree Scope = "worktree"
+)
+
+// RepoConfigPath returns the config file used for scope by the repository
+// containing dir. Git resolves the location itself, so linked worktrees and
+// submodules (whose .git is a file pointing elsewhere) are handled.
+func RepoConfigPath(dir string, scope Scope) (string, error) {
+	name := "config"
+	if scope == ScopeWorktree {
+		name = "config.worktree"
+	}
+
+	out, err := runGit(dir, "rev-parse", "--git-path", name)
+	if err != nil {
+		return "", errors.Wrap(err, "not inside a git repository")
+	}
+
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// EnableWorktreeConfig turns on extensions.worktreeConfig for the repository
+// containing dir, so git reads the per-worktree config files. Like git, it
+// upgrades the repository format version, as extensions require version 1.
+func EnableWorktreeConfig(dir string) error {
+	path, err := RepoConfigPath(dir, ScopeLocal)
+	if err != nil {
+		return err
+	}
+
+	file, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+
+	if value, _ := file.Get("extensions.worktreeConfig"); value == "true" {
+		return nil
+	}
+
+	if version, _ := file.Get("core.repositoryformatversion"); version == "" || version == "0" {
+		if err := file.Set("core.repositoryformatversion", "1"); err != nil {
+			return err
+		}
+	}
+
+	if err := file.Set("extensions.worktreeConfig", "true"); err != nil {
+		return err
+	}
+
+	if err := fsutil.WriteFile(path, file.Bytes(), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write repository config")
+	}
+
+	return nil
+}
+
+// runGit runs git in dir and returns its standard output.
+func runGit(dir string, args ...string) (string, error) {
+	return runGitWithGlobal(dir, "", args...)
+}
+
+// runGitWithGlobal runs git in dir with globalConfig as its global config
+// file, or the one git looks up itself when it is empty, and returns its
+// standard output.
+func runGitWithGlobal(dir, globalConfig string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if globalConfig != "" {
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL="+globalConfig)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", errors.Wrapf(err, "git %s: %s",
+				strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return "", errors.Wrapf(err, "git %s", strings.Join(args, " "))
+	}
+
+	return string(out), nil
+}
+
+// ProfileKey records the profile git-context wrote to a config file.
+const ProfileKey = "gitcontext.profile"
+
+// GitDir returns the absolute git directory of the repository containing dir,
+// the path includeIf "gitdir:" conditions are matched against.
+func GitDir(dir string) (string, error) {
+	out, err := runGit(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", errors.Wrap(err, "not inside a git repository")
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// RemoteURLs returns the URLs of every remote of the repository containing
+// dir, reading globalConfig as the global config file when it is set.
+func RemoteURLs(dir, globalConfig string) ([]string, error) {
+	out, err := runGitWithGlobal(dir, globalConfig, "config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		// git config exits with status 1 when no key matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var urls []string
+
+	for line := range strings.Lines(out) {
+		if _, url, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls, nil
+}
+
+// ConfigOrigin returns the value git resolves for key in the repository
+// containing dir, along with the file it comes from. Both are empty when the
+// key is not set. globalConfig, when set, is read as the global config file.
+func ConfigOrigin(dir, globalConfig, key string) (string, string, error) {
+	out, err := runGitWithGlobal(dir, globalConfig, "config", "--show-origin", "--get", key)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", "", nil
+		}
+
+		return "", "", err
+	}
+
+	origin, value, _ := strings.Cut(strings.TrimRight(out, "\n"), "\t")
+
+	return value, strings.TrimPrefix(origin, "file:"), nil
+}
+
+// RepoProfile returns the profile pinned to the repository containing dir by
+// a per-repository switch, and the scope it was written to. The worktree
+// config takes precedence, as it does in git. The profile is empty if the
+// repository is not pinned.
+func RepoProfile(dir string) (string, Scope, error) {
+	for _, scope := range []Scope{ScopeWorktree, ScopeLocal} {
+		path, err := RepoConfigPath(dir, scope)
+		if err != nil {
+			return "", "", err
+		}
+
+		file, err := ParseFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return "", "", err
+		}
+
+		if profile, ok := file.Get(ProfileKey); ok && profile != "" {
+			return profile, scope, nil
+		}
+	}
+
+	return "", "", nil
+}
@@ -0,0 +1,213 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository in a temporary directory.
+func initRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	if _, err := runGit(dir, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+
+	return dir
+}
+
+func TestRepoConfigPath(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	path, err := RepoConfigPath(dir, ScopeLocal)
+	if err != nil {
+		t.Fatalf("RepoConfigPath failed: %v", err)
+	}
+
+	want, _ := filepath.EvalSymlinks(filepath.Join(dir, ".git", "config"))
+	if got, _ := filepath.EvalSymlinks(path); got != want {
+		t.Errorf("RepoConfigPath(local) = %s, want %s", path, want)
+	}
+
+	path, err = RepoConfigPath(dir, ScopeWorktree)
+	if err != nil {
+		t.Fatalf("RepoConfigPath failed: %v", err)
+	}
+
+	if filepath.Base(path) != "config.worktree" {
+		t.Errorf("RepoConfigPath(worktree) = %s, want a config.worktree file", path)
+	}
+}
+
+// Not parallel: it sets an environment variable.
+func TestRepoConfigPathOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	if _, err := RepoConfigPath(dir, ScopeLocal); err == nil {
+		t.Error("RepoConfigPath should fail outside a repository")
+	}
+}
+
+func TestEnableWorktreeConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	if err := EnableWorktreeConfig(dir); err != nil {
+		t.Fatalf("EnableWorktreeConfig failed: %v", err)
+	}
+
+	// Enabling twice leaves the config unchanged
+	configPath := filepath.Join(dir, ".git", "config")
+
+	before, _ := os.ReadFile(configPath)
+	if err := EnableWorktreeConfig(dir); err != nil {
+		t.Fatalf("EnableWorktreeConfig failed: %v", err)
+	}
+
+	after, _ := os.ReadFile(configPath)
+	if string(before) != string(after) {
+		t.Errorf("Second call modified the config:\n%s", after)
+	}
+
+	out, err := runGit(dir, "config", "--get", "extensions.worktreeConfig")
+	if err != nil || strings.TrimSpace(out) != "true" {
+		t.Errorf("extensions.worktreeConfig = %q (%v), want true", out, err)
+	}
+
+	// git accepts writes to the worktree config once the extension is enabled
+	if _, err := runGit(dir, "config", "--worktree", "user.name", "Test"); err != nil {
+		t.Errorf("git config --worktree failed: %v", err)
+	}
+}
+
+func TestLocalWritePreservesRepositorySettings(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	if _, err := runGit(dir, "remote", "add", "origin", "https://example.com/repo.git"); err != nil {
+		t.Fatalf("git remote add failed: %v", err)
+	}
+
+	configPath, err := RepoConfigPath(dir, ScopeLocal)
+	if err != nil {
+		t.Fatalf("RepoConfigPath failed: %v", err)
+	}
+
+	g := NewGit(configPath, WithMode(ModeBlock))
+	if err := g.WriteConfig(map[string]any{"user.email": "repo@example.com"}); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	out, err := runGit(dir, "config", "--get", "remote.origin.url")
+	if err != nil || strings.TrimSpace(out) != "https://example.com/repo.git" {
+		t.Errorf("remote.origin.url = %q (%v), want it preserved", out, err)
+	}
+
+	out, err = runGit(dir, "config", "--local", "--get", "user.email")
+	if err != nil || strings.TrimSpace(out) != "repo@example.com" {
+		t.Errorf("user.email = %q (%v), want repo@example.com", out, err)
+	}
+}
+
+func TestRepositoryQueries(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	gitDir, err := GitDir(dir)
+	if err != nil {
+		t.Fatalf("GitDir failed: %v", err)
+	}
+
+	if filepath.Base(gitDir) != ".git" || !filepath.IsAbs(gitDir) {
+		t.Errorf("GitDir() = %s, want an absolute .git directory", gitDir)
+	}
+
+	urls, err := RemoteURLs(dir, "")
+	if err != nil || len(urls) != 0 {
+		t.Errorf("RemoteURLs() = %v (%v), want none", urls, err)
+	}
+
+	if _, err := runGit(dir, "remote", "add", "origin", "git@github.com:corp/api.git"); err != nil {
+		t.Fatalf("git remote add failed: %v", err)
+	}
+
+	urls, err = RemoteURLs(dir, "")
+	if err != nil || !slices.Equal(urls, []string{"git@github.com:corp/api.git"}) {
+		t.Errorf("RemoteURLs() = %v (%v)", urls, err)
+	}
+
+	if _, err := runGit(dir, "config", "--local", "user.email", "repo@example.com"); err != nil {
+		t.Fatalf("git config failed: %v", err)
+	}
+
+	value, origin, err := ConfigOrigin(dir, "", "user.email")
+	if err != nil || value != "repo@example.com" || origin != ".git/config" {
+		t.Errorf("ConfigOrigin() = (%q, %q, %v)", value, origin, err)
+	}
+
+	// The global config given is read instead of the user's own
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(global, []byte("[gitcontext]\n\tglobal = given\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+
+	value, origin, err = ConfigOrigin(dir, global, "gitcontext.global")
+	if err != nil || value != "given" || origin != global {
+		t.Errorf("ConfigOrigin() with a global config = (%q, %q, %v)", value, origin, err)
+	}
+
+	value, _, err = ConfigOrigin(dir, "", "gitcontext.missing")
+	if err != nil || value != "" {
+		t.Errorf("ConfigOrigin() of a missing key = (%q, %v), want empty", value, err)
+	}
+}
+
+func TestRepoProfile(t *testing.T) {
+	t.Parallel()
+
+	dir := initRepo(t)
+
+	profile, _, err := RepoProfile(dir)
+	if err != nil || profile != "" {
+		t.Errorf("RepoProfile() = (%q, %v), want no pin", profile, err)
+	}
+
+	localPath, _ := RepoConfigPath(dir, ScopeLocal)
+	if err := NewGit(localPath, WithMode(ModeBlock)).WriteConfig(map[string]any{ProfileKey: "work"}); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	profile, scope, err := RepoProfile(dir)
+	if err != nil || profile != "work" || scope != ScopeLocal {
+		t.Errorf("RepoProfile() = (%q, %q, %v), want work pinned locally", profile, scope, err)
+	}
+
+	worktreePath, _ := RepoConfigPath(dir, ScopeWorktree)
+	if err := NewGit(worktreePath, WithMode(ModeBlock)).WriteConfig(map[string]any{ProfileKey: "client"}); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	profile, scope, err = RepoProfile(dir)
+	if err != nil || profile != "client" || scope != ScopeWorktree {
+		t.Errorf("RepoProfile() = (%q, %q, %v), want client pinned to the worktree", profile, scope, err)
+	}
+}
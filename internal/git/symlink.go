@@ -0,0 +1,72 @@
+package git
+
+import (
+	"github.com/aanogueira/git-context/internal/fsutil"
+	"github.com/cockroachdb/errors"
+)
+
+// SymlinkPolicy defines how a git config that is a symbolic link, for example
+// into a dotfiles repository, is written.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow writes the file the link points to and keeps the link.
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkRefuse fails instead of writing through a link.
+	SymlinkRefuse SymlinkPolicy = "refuse"
+)
+
+// ParseSymlinkPolicy validates a symlink policy name. An empty name selects SymlinkFollow.
+func ParseSymlinkPolicy(name string) (SymlinkPolicy, error) {
+	switch SymlinkPolicy(name) {
+	case "", SymlinkFollow:
+		return SymlinkFollow, nil
+	case SymlinkRefuse:
+		return SymlinkRefuse, nil
+	default:
+		return "", errors.WithStack(errors.Newf("unknown symlink policy '%s'", name))
+	}
+}
+
+// WithSymlinkPolicy sets how symbolic links are written. A link is never
+// replaced by a regular file: it is either followed or the write fails.
+func WithSymlinkPolicy(policy SymlinkPolicy) Option {
+	return func(g *Git) {
+		g.symlinks = policy
+	}
+}
+
+// CheckSymlink returns the file written for path: path itself, or the file a
+// symbolic link at path points to. Under SymlinkRefuse, a link is an error.
+func CheckSymlink(path string, policy SymlinkPolicy) (string, error) {
+	target, err := fsutil.ResolveSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	if target != path && policy == SymlinkRefuse {
+		return "", errors.WithStack(errors.Newf(
+			"%s is a symbolic link to %s and settings.symlinks is '%s'; "+
+				"set it to '%s' to write the link target, or replace the link with a regular file",
+			path, target, SymlinkRefuse, SymlinkFollow,
+		))
+	}
+
+	return target, nil
+}
+
+// checkSymlinks applies the symlink policy to the files written in the mode.
+func (g *Git) checkSymlinks() error {
+	paths := []string{g.globalConfigPath}
+	if g.mode == ModeInclude {
+		paths = append(paths, g.includePath)
+	}
+
+	for _, path := range paths {
+		if _, err := CheckSymlink(path, g.symlinks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]SymlinkPolicy{"": SymlinkFollow, "follow": SymlinkFollow, "refuse": SymlinkRefuse} {
+		if got, err := ParseSymlinkPolicy(name); err != nil || got != want {
+			t.Errorf("ParseSymlinkPolicy(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseSymlinkPolicy("replace"); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
+
+func TestWriteConfigSymlink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "gitconfig")
+	link := filepath.Join(dir, ".gitconfig")
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	if err := os.WriteFile(target, []byte("[core]\n\teditor = vim\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	config := map[string]any{"user.name": "Test User"}
+
+	for _, mode := range []Mode{ModeOverwrite, ModeBlock} {
+		err := NewGit(link, WithMode(mode), WithSymlinkPolicy(SymlinkRefuse)).WriteConfig(config)
+		if err == nil || !strings.Contains(err.Error(), "symbolic link") {
+			t.Errorf("Expected %s mode to refuse writing through the link, got %v", mode, err)
+		}
+	}
+
+	if data, _ := os.ReadFile(target); string(data) != "[core]\n\teditor = vim\n" {
+		t.Errorf("Expected a refused write to leave the target alone, got %q", data)
+	}
+
+	if err := NewGit(link, WithMode(ModeBlock)).WriteConfig(config); err != nil {
+		t.Fatalf("WriteConfig failed: %v", err)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the link to be kept, got %v (%v)", info, err)
+	}
+
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "name = Test User") ||
+		!strings.Contains(string(data), "editor = vim") {
+		t.Errorf("Expected the link target to be updated, got %q", data)
+	}
+
+	backup := filepath.Join(dir, "backup")
+	if err := NewGit(link).BackupConfig(backup); err != nil {
+		t.Fatalf("BackupConfig failed: %v", err)
+	}
+
+	if info, err := os.Lstat(backup); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Expected the backup to be a regular file, got %v (%v)", info, err)
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/cockroachdb/errors"
 	"github.com/fatih/color"
@@ -18,43 +19,73 @@ const (
 	OutputInfo
 )
 
+// colors holds the color of each output type.
+var colors = map[OutputType]color.Attribute{
+	OutputSuccess: color.FgGreen,
+	OutputError:   color.FgRed,
+	OutputWarning: color.FgYellow,
+	OutputInfo:    color.FgCyan,
+}
+
 // Print prints colored output.
 func Print(outputType OutputType, message string) {
-	switch outputType {
-	case OutputSuccess:
-		color.Green(message)
-	case OutputError:
-		color.Red(message)
-	case OutputWarning:
-		color.Yellow(message)
-	case OutputInfo:
-		color.Cyan(message)
-	}
+	Fprint(color.Output, outputType, message)
+}
+
+// Fprint prints colored output to w, such as standard error for the status
+// of commands whose standard output is data.
+func Fprint(w io.Writer, outputType OutputType, message string) {
+	_, _ = color.New(colors[outputType]).Fprintln(w, message)
 }
 
 // PrintHeader prints a formatted header.
 func PrintHeader(title string) {
-	color.Cyan("\n=== %s ===\n", title)
+	FprintHeader(color.Output, title)
+}
+
+// FprintHeader prints a formatted header to w.
+func FprintHeader(w io.Writer, title string) {
+	_, _ = color.New(color.FgCyan).Fprintf(w, "\n=== %s ===\n", title)
 }
 
 // PrintSuccess prints a success message.
 func PrintSuccess(message string) {
-	Print(OutputSuccess, "✓ "+message)
+	FprintSuccess(color.Output, message)
+}
+
+// FprintSuccess prints a success message to w.
+func FprintSuccess(w io.Writer, message string) {
+	Fprint(w, OutputSuccess, "✓ "+message)
 }
 
 // PrintError prints an error message.
 func PrintError(message string) {
-	Print(OutputError, "✗ "+message)
+	FprintError(color.Output, message)
+}
+
+// FprintError prints an error message to w.
+func FprintError(w io.Writer, message string) {
+	Fprint(w, OutputError, "✗ "+message)
 }
 
 // PrintWarning prints a warning message.
 func PrintWarning(message string) {
-	Print(OutputWarning, "⚠ "+message)
+	FprintWarning(color.Output, message)
+}
+
+// FprintWarning prints a warning message to w.
+func FprintWarning(w io.Writer, message string) {
+	Fprint(w, OutputWarning, "⚠ "+message)
 }
 
 // PrintInfo prints an info message.
 func PrintInfo(message string) {
-	Print(OutputInfo, "ℹ "+message)
+	FprintInfo(color.Output, message)
+}
+
+// FprintInfo prints an info message to w.
+func FprintInfo(w io.Writer, message string) {
+	Fprint(w, OutputInfo, "ℹ "+message)
 }
 
 // PromptText prompts for text input.
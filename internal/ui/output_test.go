@@ -161,6 +161,20 @@ func TestPrint(t *testing.T) {
 	}
 }
 
+func TestFprint(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	FprintWarning(&buf, "Warning message")
+	FprintHeader(&buf, "Header")
+
+	output := buf.String()
+	if !strings.Contains(output, "⚠ Warning message") || !strings.Contains(output, "=== Header ===") {
+		t.Errorf("Expected the messages to be written to the writer, got %q", output)
+	}
+}
+
 func TestPrintTable(t *testing.T) {
 	t.Parallel()
 